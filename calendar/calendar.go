@@ -0,0 +1,192 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/orayew2002/rast-excel/domain"
+)
+
+// Kind classifies a calendar day.
+type Kind int
+
+const (
+	Workday Kind = iota // regular working day
+	RestDay             // weekly rest day (e.g. Saturday, Sunday)
+	Holiday             // public holiday
+)
+
+// Calendar knows which days are weekly rest days, public holidays and
+// transferred working days (rest days that are worked instead).
+//
+// RestCode and HolidayCode are the attendance symbols written by Fill on
+// non-working days. Both default to "B" ("Dynç alyş we baýramçylyk günler"),
+// the legend's code for days off and holidays, which no worked total counts.
+type Calendar struct {
+	RestCode    string
+	HolidayCode string
+
+	rest     map[time.Weekday]bool
+	holidays map[string]string   // "2006-01-02" → holiday name
+	workdays map[string]struct{} // transferred working days
+}
+
+// New creates a Calendar with the given weekly rest days.
+// With no arguments Saturday and Sunday are rest days.
+func New(rest ...time.Weekday) *Calendar {
+	if len(rest) == 0 {
+		rest = []time.Weekday{time.Saturday, time.Sunday}
+	}
+
+	c := &Calendar{
+		RestCode:    "B",
+		HolidayCode: "B",
+		rest:        make(map[time.Weekday]bool),
+		holidays:    make(map[string]string),
+		workdays:    make(map[string]struct{}),
+	}
+	for _, d := range rest {
+		c.rest[d] = true
+	}
+	return c
+}
+
+// AddHoliday marks date as a public holiday with the given name.
+func (c *Calendar) AddHoliday(date time.Time, name string) {
+	c.holidays[dateKey(date)] = name
+}
+
+// AddWorkday marks date as a transferred working day. A transferred working
+// day is worked even if it falls on a weekly rest day.
+func (c *Calendar) AddWorkday(date time.Time) {
+	c.workdays[dateKey(date)] = struct{}{}
+}
+
+// Kind classifies date. Holidays take precedence over transferred working days,
+// which take precedence over weekly rest days.
+func (c *Calendar) Kind(date time.Time) Kind {
+	key := dateKey(date)
+	if _, ok := c.holidays[key]; ok {
+		return Holiday
+	}
+	if _, ok := c.workdays[key]; ok {
+		return Workday
+	}
+	if c.rest[date.Weekday()] {
+		return RestDay
+	}
+	return Workday
+}
+
+// IsWorkday reports whether date is a working day.
+func (c *Calendar) IsWorkday(date time.Time) bool {
+	return c.Kind(date) == Workday
+}
+
+// HolidayName returns the name of the holiday on date, or "" if there is none.
+func (c *Calendar) HolidayName(date time.Time) string {
+	return c.holidays[dateKey(date)]
+}
+
+// Kinds returns the kind of every day of p, in order.
+func (c *Calendar) Kinds(p domain.Period) []Kind {
+	kinds := make([]Kind, p.Days())
+	for i := range kinds {
+		kinds[i] = c.Kind(p.Day(i))
+	}
+	return kinds
+}
+
+// Fill overwrites the attendance entries of non-working days in p with
+// RestCode or HolidayCode. attendance[i] is the entry for p.Day(i); entries
+// beyond len(attendance) are ignored.
+func (c *Calendar) Fill(p domain.Period, attendance []string) {
+	for i := range min(len(attendance), p.Days()) {
+		switch c.Kind(p.Day(i)) {
+		case RestDay:
+			attendance[i] = c.RestCode
+		case Holiday:
+			attendance[i] = c.HolidayCode
+		}
+	}
+}
+
+// LoadFile reads holidays and transferred working days from a file.
+// See Load for the format.
+func (c *Calendar) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open calendar: %w", err)
+	}
+	defer file.Close()
+
+	if err := c.Load(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads holidays and transferred working days, one per line, the date
+// separated from the rest by spaces or tabs:
+//
+//	# comment
+//	2026-01-01 Täze ýyl          ← holiday with a name
+//	2026-05-18                   ← holiday without a name
+//	2026-03-07 workday           ← transferred working day
+func (c *Calendar) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// The date ends at the first space or tab; the name keeps its own.
+		dateText, rest := text, ""
+		if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+			dateText, rest = text[:i], text[i:]
+		}
+		date, err := time.ParseInLocation(time.DateOnly, dateText, time.Local)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rest = strings.TrimSpace(rest)
+		if strings.EqualFold(rest, "workday") {
+			c.AddWorkday(date)
+			continue
+		}
+		c.AddHoliday(date, rest)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read calendar: %w", err)
+	}
+	return nil
+}
+
+func dateKey(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+// weekdayAbbrs holds weekday abbreviations per locale, indexed by time.Weekday.
+var weekdayAbbrs = map[string][7]string{
+	"tk": {"Ýe", "Du", "Si", "Ça", "Pe", "An", "Şe"},
+	"ru": {"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"},
+	"en": {"Su", "Mo", "Tu", "We", "Th", "Fr", "Sa"},
+}
+
+// WeekdayAbbr returns the abbreviation of wd in locale ("tk", "ru" or "en").
+// Unknown locales fall back to "tk".
+func WeekdayAbbr(locale string, wd time.Weekday) string {
+	abbrs, ok := weekdayAbbrs[locale]
+	if !ok {
+		abbrs = weekdayAbbrs["tk"]
+	}
+	return abbrs[wd]
+}
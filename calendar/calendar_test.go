@@ -0,0 +1,54 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	data := "# Turkmenistan, 2026\n" +
+		"2026-01-01 Täze ýyl\n" +
+		"2026-03-08\tHalkara  zenanlar güni\n" +
+		"  2026-05-18  \n" +
+		"\n" +
+		"2026-03-07\t\tworkday\n" +
+		"2026-03-14  WORKDAY\n"
+
+	c := New()
+	if err := c.Load(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.ParseInLocation(time.DateOnly, s, time.Local)
+		return d
+	}
+	tests := []struct {
+		date string
+		kind Kind
+		name string
+	}{
+		{"2026-01-01", Holiday, "Täze ýyl"},
+		{"2026-03-08", Holiday, "Halkara  zenanlar güni"},
+		{"2026-05-18", Holiday, ""},
+		{"2026-03-07", Workday, ""},
+		{"2026-03-14", Workday, ""},
+		{"2026-03-15", RestDay, ""},
+	}
+	for _, tt := range tests {
+		if got := c.Kind(day(tt.date)); got != tt.kind {
+			t.Errorf("Kind(%s) = %v, want %v", tt.date, got, tt.kind)
+		}
+		if got := c.HolidayName(day(tt.date)); got != tt.name {
+			t.Errorf("HolidayName(%s) = %q, want %q", tt.date, got, tt.name)
+		}
+	}
+}
+
+func TestLoadBadDate(t *testing.T) {
+	err := New().Load(strings.NewReader("2026-01-01 Täze ýyl\n2026-13-01\tX\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("Load error = %v, want a line 2 error", err)
+	}
+}
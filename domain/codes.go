@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Code is one attendance code: the symbol written into attendance cells and
+// what it means for the marks legend, the formulas and input validation.
+type Code struct {
+	Symbol string  // cell text, e.g. "B"; letters only
+	Name   string  // legend name; "" keeps the code out of the legend
+	Worked bool    // a day with this code counts as worked time
+	Hours  float64 // hours credited per day with this code (e.g. 8)
+	Key    string  // formula key without braces (e.g. "b"); "" for none
+}
+
+// Codes is a set of attendance codes, in legend order.
+type Codes struct {
+	list   []Code
+	index  map[string]int
+	legend []Mark // explicit legend set by SetLegend; nil = derived from Name
+}
+
+// NewCodes builds a code set. Symbols must be letters and unique; formula
+// keys must be unique.
+func NewCodes(codes ...Code) (*Codes, error) {
+	c := &Codes{index: make(map[string]int)}
+	keys := make(map[string]string)
+	for _, code := range codes {
+		if code.Symbol == "" || strings.IndexFunc(code.Symbol, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+			return nil, fmt.Errorf("code %q: symbol must be letters only", code.Symbol)
+		}
+		if _, ok := c.index[code.Symbol]; ok {
+			return nil, fmt.Errorf("code %q: duplicate symbol", code.Symbol)
+		}
+		if code.Hours < 0 || code.Hours > 24 {
+			return nil, fmt.Errorf("code %q: hours %v outside 0..24", code.Symbol, code.Hours)
+		}
+		if code.Key != "" {
+			if other, ok := keys[code.Key]; ok {
+				return nil, fmt.Errorf("code %q: formula key %q already used by %q", code.Symbol, code.Key, other)
+			}
+			keys[code.Key] = code.Symbol
+		}
+		c.index[code.Symbol] = len(c.list)
+		c.list = append(c.list, code)
+	}
+	return c, nil
+}
+
+// All returns the codes in legend order.
+func (c *Codes) All() []Code {
+	return c.list
+}
+
+// Lookup returns the code with the given symbol.
+func (c *Codes) Lookup(symbol string) (Code, bool) {
+	i, ok := c.index[symbol]
+	if !ok {
+		return Code{}, false
+	}
+	return c.list[i], true
+}
+
+// SetLegend replaces the legend derived from the code names with marks, for
+// legends that list a code more than once or in a different order. Every
+// mark's Key must be the symbol of a code in the set.
+func (c *Codes) SetLegend(marks []Mark) error {
+	for _, m := range marks {
+		if _, ok := c.index[m.Key]; !ok {
+			return fmt.Errorf("legend %q: unknown code %q", m.Name, m.Key)
+		}
+	}
+	c.legend = append([]Mark(nil), marks...)
+	return nil
+}
+
+// Marks returns the legend: the one set by SetLegend, or else the entries of
+// the codes that have a name.
+func (c *Codes) Marks() []Mark {
+	if c.legend != nil {
+		return append([]Mark(nil), c.legend...)
+	}
+
+	var marks []Mark
+	for _, code := range c.list {
+		if code.Name != "" {
+			marks = append(marks, Mark{Name: code.Name, Key: code.Symbol})
+		}
+	}
+	return marks
+}
+
+// Validate checks that every attendance cell is empty, plain hours or a known
+// code with optional hours (see ParseEntry).
+func (c *Codes) Validate(attendance []string) error {
+	for i, s := range attendance {
+		e, err := ParseEntry(s)
+		if err != nil {
+			return fmt.Errorf("day %d: %w", i+1, err)
+		}
+		if e.Code == "" {
+			continue
+		}
+		if _, ok := c.Lookup(e.Code); !ok {
+			return fmt.Errorf("day %d: unknown attendance code %q", i+1, e.Code)
+		}
+	}
+	return nil
+}
+
+// DefaultCodes returns the codes of the standard timesheet legend, plus the
+// T, D, L, A and P codes counted by the {{t}}, {{d}}, {{l}}, {{a}} and {{p}}
+// formula keys. Plain hours ("8") need no code.
+//
+// The legend is the standard one, in which "O" covers both regular leave and
+// maternity leave ("Gowrelilik sebäpli rugsat") on separate lines.
+func DefaultCodes() *Codes {
+	codes, err := NewCodes(
+		Code{Symbol: "B", Name: "Dynç alyş we baýramçylyk günler", Key: "b"},
+		Code{Symbol: "C", Name: "Kanuna laýyk işe gelmezlik", Key: "c"},
+		Code{Symbol: "W", Name: "Gulluk iş saparlary", Worked: true, Hours: 8, Key: "w"},
+		Code{Symbol: "O", Name: "Nobatdaky we goşmaça rugsatlar", Key: "o"},
+		Code{Symbol: "Y", Name: "Işe ýarawsyzlyk (kesel, karantin we ş.m.)", Key: "y"},
+		Code{Symbol: "I", Name: "Emdiryän eneleriň ýeňillikli sagatlary", Worked: true, Key: "i"},
+		Code{Symbol: "ÝS", Name: "Saglyga zyýanly önümçilikde işleýän işleriň ýeňillikli sagatlary", Worked: true, Key: "ýs"},
+		Code{Symbol: "IWI", Name: "Iş wagtyndan daşary edilen işiň sagatlary", Worked: true, Key: "iwi"},
+		Code{Symbol: "ID", Name: "Bütin smena boýunça işsiz durmaklyk", Key: "id"},
+		Code{Symbol: "IID", Name: "Smeniň içindäki işsiz durmaklyk", Key: "iid"},
+		Code{Symbol: "S", Name: "Sebäpsiz işden galmak", Key: "s"},
+		Code{Symbol: "SIG", Name: "Işe gijä galmak we işden wagtyndan öň gitmek", Key: "sig"},
+		Code{Symbol: "ÇDG", Name: "Kärhanañ çäginden daşary gulluk tabşyryklaryny ýerine ýetirmek", Worked: true, Hours: 8, Key: "çdg"},
+		Code{Symbol: "AR", Name: "Administrasiýañ rugsady boýunça işe gelmezlik", Key: "ar"},
+		Code{Symbol: "T", Worked: true, Hours: 8, Key: "t"},
+		Code{Symbol: "D", Key: "d"},
+		Code{Symbol: "L", Key: "l"},
+		Code{Symbol: "A", Key: "a"},
+		Code{Symbol: "P", Key: "p"},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	err = codes.SetLegend([]Mark{
+		{Name: "Dynç alyş we baýramçylyk günler", Key: "B"},
+		{Name: "Kanuna laýyk işe gelmezlik", Key: "C"},
+		{Name: "Gulluk iş saparlary", Key: "W"},
+		{Name: "Nobatdaky we goşmaça rugsatlar", Key: "O"},
+		{Name: "Işe ýarawsyzlyk (kesel, karantin we ş.m.)", Key: "Y"},
+		{Name: "Gowrelilik sebäpli rugsat", Key: "O"},
+		{Name: "Emdiryän eneleriň ýeňillikli sagatlary", Key: "I"},
+		{Name: "Saglyga zyýanly önümçilikde işleýän işleriň ýeňillikli sagatlary", Key: "ÝS"},
+		{Name: "Iş wagtyndan daşary edilen işiň sagatlary", Key: "IWI"},
+		{Name: "Bütin smena boýunça işsiz durmaklyk", Key: "ID"},
+		{Name: "Smeniň içindäki işsiz durmaklyk", Key: "IID"},
+		{Name: "Sebäpsiz işden galmak", Key: "S"},
+		{Name: "Işe gijä galmak we işden wagtyndan öň gitmek", Key: "SIG"},
+		{Name: "Kärhanañ çäginden daşary gulluk tabşyryklaryny ýerine ýetirmek", Key: "ÇDG"},
+		{Name: "Administrasiýañ rugsady boýunça işe gelmezlik", Key: "AR"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return codes
+}
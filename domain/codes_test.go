@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// baselineMarks is the {{marks_list}} legend the CLI printed before the
+// attendance codes were introduced.
+var baselineMarks = []Mark{
+	{Name: "Dynç alyş we baýramçylyk günler", Key: "B"},
+	{Name: "Kanuna laýyk işe gelmezlik", Key: "C"},
+	{Name: "Gulluk iş saparlary", Key: "W"},
+	{Name: "Nobatdaky we goşmaça rugsatlar", Key: "O"},
+	{Name: "Işe ýarawsyzlyk (kesel, karantin we ş.m.)", Key: "Y"},
+	{Name: "Gowrelilik sebäpli rugsat", Key: "O"},
+	{Name: "Emdiryän eneleriň ýeňillikli sagatlary", Key: "I"},
+	{Name: "Saglyga zyýanly önümçilikde işleýän işleriň ýeňillikli sagatlary", Key: "ÝS"},
+	{Name: "Iş wagtyndan daşary edilen işiň sagatlary", Key: "IWI"},
+	{Name: "Bütin smena boýunça işsiz durmaklyk", Key: "ID"},
+	{Name: "Smeniň içindäki işsiz durmaklyk", Key: "IID"},
+	{Name: "Sebäpsiz işden galmak", Key: "S"},
+	{Name: "Işe gijä galmak we işden wagtyndan öň gitmek", Key: "SIG"},
+	{Name: "Kärhanañ çäginden daşary gulluk tabşyryklaryny ýerine ýetirmek", Key: "ÇDG"},
+	{Name: "Administrasiýañ rugsady boýunça işe gelmezlik", Key: "AR"},
+}
+
+func TestDefaultCodesMarksMatchBaseline(t *testing.T) {
+	if got := DefaultCodes().Marks(); !reflect.DeepEqual(got, baselineMarks) {
+		t.Errorf("DefaultCodes().Marks() =\n%v\nwant\n%v", got, baselineMarks)
+	}
+}
+
+func TestMarksDerivedFromNames(t *testing.T) {
+	codes, err := NewCodes(
+		Code{Symbol: "B", Name: "Rest"},
+		Code{Symbol: "T"},
+		Code{Symbol: "W", Name: "Trip"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mark{{Name: "Rest", Key: "B"}, {Name: "Trip", Key: "W"}}
+	if got := codes.Marks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Marks() = %v, want %v", got, want)
+	}
+}
+
+func TestSetLegendRejectsUnknownCode(t *testing.T) {
+	codes, err := NewCodes(Code{Symbol: "B", Name: "Rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := codes.SetLegend([]Mark{{Name: "Other", Key: "Q"}}); err == nil {
+		t.Error("SetLegend with an unknown code: want an error")
+	}
+}
@@ -3,17 +3,19 @@ package domain
 import (
 	"fmt"
 	"math/rand/v2"
-	"time"
 
 	"github.com/bxcodec/faker/v4"
 )
 
+// Employee is one timesheet row. Fields holds extra values (department, rate,
+// hire date…) that templates reference by key, e.g. {{.Department}}.
 type Employee struct {
 	Id          int
 	FullName    string
 	TableID     string
 	JobPosition string
 	Attendance  []string
+	Fields      map[string]string
 }
 
 // Mark represents a single attendance legend entry.
@@ -33,12 +35,16 @@ var jobPositions = []string{
 	"Project Manager",
 }
 
-var attendanceSymbols = []string{"W", "8", "P", "W", "A", "L"}
-
 // GenerateEmployees creates n employees with random data.
 // Attendance length matches the current month's day count.
 func GenerateEmployees(n int) []Employee {
-	days := currentMonthDays()
+	return GenerateEmployeesForPeriod(n, CurrentPeriod())
+}
+
+// GenerateEmployeesForPeriod creates n employees with random data.
+// Attendance length matches the number of days in p.
+func GenerateEmployeesForPeriod(n int, p Period) []Employee {
+	days := p.Days()
 	employees := make([]Employee, n)
 
 	for i := range n {
@@ -54,17 +60,17 @@ func GenerateEmployees(n int) []Employee {
 	return employees
 }
 
+// generateAttendance draws worked 8-hour days half of the time and codes of
+// DefaultCodes otherwise.
 func generateAttendance(days int) []string {
+	codes := DefaultCodes().All()
 	attendance := make([]string, days)
 	for i := range attendance {
-		attendance[i] = attendanceSymbols[rand.IntN(len(attendanceSymbols))]
+		if rand.IntN(2) == 0 {
+			attendance[i] = "8"
+			continue
+		}
+		attendance[i] = codes[rand.IntN(len(codes))].Symbol
 	}
 	return attendance
 }
-
-func currentMonthDays() int {
-	now := time.Now().Local()
-	year, month, _ := now.Date()
-	first := time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location())
-	return first.AddDate(0, 0, -1).Day()
-}
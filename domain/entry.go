@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Entry is one attendance cell: an optional code with the hours worked.
+//
+//	"8"       → Hours 8
+//	"4.5"     → Hours 4.5
+//	"8/2"     → Hours 8, Night 2
+//	"Y 8"     → Code "Y", Hours 8
+//	"W"       → Code "W"
+//	"8/2+1.5" → Hours 8, Night 2, Overtime 1.5
+type Entry struct {
+	Code     string  // attendance code (e.g. "Y", "W"); "" for plain hours
+	Hours    float64 // day hours
+	Night    float64 // night hours
+	Overtime float64 // overtime hours
+}
+
+// ParseEntry parses the text of an attendance cell:
+//
+//	[CODE] [HOURS[/NIGHT][+OVERTIME]]
+//
+// CODE is a run of letters; hours use "." or "," as decimal separator. An
+// empty cell is the zero Entry.
+func ParseEntry(s string) (Entry, error) {
+	s = strings.TrimSpace(s)
+	rest := strings.TrimLeftFunc(s, unicode.IsLetter)
+	e := Entry{Code: s[:len(s)-len(rest)]}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return e, nil
+	}
+
+	rest, overtime, hasOvertime := strings.Cut(rest, "+")
+	hours, night, hasNight := strings.Cut(rest, "/")
+
+	var err error
+	if e.Hours, err = parseHours(hours); err != nil {
+		return Entry{}, fmt.Errorf("attendance %q: hours: %w", s, err)
+	}
+	if hasNight {
+		if e.Night, err = parseHours(night); err != nil {
+			return Entry{}, fmt.Errorf("attendance %q: night hours: %w", s, err)
+		}
+	}
+	if hasOvertime {
+		if e.Overtime, err = parseHours(overtime); err != nil {
+			return Entry{}, fmt.Errorf("attendance %q: overtime: %w", s, err)
+		}
+	}
+	return e, nil
+}
+
+func parseHours(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if v < 0 || v > 24 {
+		return 0, fmt.Errorf("%s is outside 0..24", s)
+	}
+	return v, nil
+}
+
+// String renders e as cell text in the canonical form read by ParseEntry and
+// by the hours formulas: "Y 8/2+1". Zero hours are left out, so the zero
+// Entry is "".
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Code)
+	if e.Hours == 0 && e.Night == 0 && e.Overtime == 0 {
+		return b.String()
+	}
+	if e.Code != "" {
+		b.WriteByte(' ')
+	}
+	b.WriteString(formatHours(e.Hours))
+	if e.Night != 0 {
+		b.WriteString("/" + formatHours(e.Night))
+	}
+	if e.Overtime != 0 {
+		b.WriteString("+" + formatHours(e.Overtime))
+	}
+	return b.String()
+}
+
+func formatHours(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// ParseAttendance parses every attendance cell of an employee.
+func ParseAttendance(attendance []string) ([]Entry, error) {
+	entries := make([]Entry, len(attendance))
+	for i, s := range attendance {
+		e, err := ParseEntry(s)
+		if err != nil {
+			return nil, fmt.Errorf("day %d: %w", i+1, err)
+		}
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+// FormatAttendance renders entries as attendance cell text.
+func FormatAttendance(entries []Entry) []string {
+	attendance := make([]string, len(entries))
+	for i, e := range entries {
+		attendance[i] = e.String()
+	}
+	return attendance
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Entry
+	}{
+		{"", Entry{}},
+		{"  ", Entry{}},
+		{"8", Entry{Hours: 8}},
+		{"4.5", Entry{Hours: 4.5}},
+		{"4,5", Entry{Hours: 4.5}},
+		{"8/2", Entry{Hours: 8, Night: 2}},
+		{"8+1.5", Entry{Hours: 8, Overtime: 1.5}},
+		{"8/2+1,5", Entry{Hours: 8, Night: 2, Overtime: 1.5}},
+		{"W", Entry{Code: "W"}},
+		{"Y 8", Entry{Code: "Y", Hours: 8}},
+		{"Y8", Entry{Code: "Y", Hours: 8}},
+		{" Y  8 ", Entry{Code: "Y", Hours: 8}},
+		{"Y 4/2+1", Entry{Code: "Y", Hours: 4, Night: 2, Overtime: 1}},
+		{"ÝS 6", Entry{Code: "ÝS", Hours: 6}},
+		{"IWI 2", Entry{Code: "IWI", Hours: 2}},
+		{"0", Entry{}},
+		{"24", Entry{Hours: 24}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEntry(tt.in)
+		if err != nil {
+			t.Errorf("ParseEntry(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEntry(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseEntryErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"-1", `attendance "-1": hours: -1 is outside 0..24`},
+		{"Y -4", `attendance "Y -4": hours: -4 is outside 0..24`},
+		{"8/-2", `attendance "8/-2": night hours: -2 is outside 0..24`},
+		{"8+-1", `attendance "8+-1": overtime: -1 is outside 0..24`},
+		{"25", `attendance "25": hours: 25 is outside 0..24`},
+		{"8Y", `attendance "8Y": hours: invalid number "8Y"`},
+		{"Y-8", `attendance "Y-8": hours: -8 is outside 0..24`},
+		{"W 8 Y", `attendance "W 8 Y": hours: invalid number "8 Y"`},
+		{"#", `attendance "#": hours: invalid number "#"`},
+		{"Y 8/", `attendance "Y 8/": night hours: invalid number ""`},
+		{"8+", `attendance "8+": overtime: invalid number ""`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseEntry(tt.in)
+		if err == nil {
+			t.Errorf("ParseEntry(%q): want an error", tt.in)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("ParseEntry(%q) error = %q, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestEntryStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // canonical text
+	}{
+		{"", ""},
+		{"8", "8"},
+		{"4,5", "4.5"},
+		{"8/2", "8/2"},
+		{"8+1.5", "8+1.5"},
+		{"Y8", "Y 8"},
+		{"Y 4/2+1", "Y 4/2+1"},
+		{"W", "W"},
+		{"Y 0", "Y"},
+		{"0/2", "0/2"},
+		{"ÝS 6.25", "ÝS 6.25"},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseEntry(tt.in)
+		if err != nil {
+			t.Errorf("ParseEntry(%q): %v", tt.in, err)
+			continue
+		}
+		got := e.String()
+		if got != tt.want {
+			t.Errorf("ParseEntry(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+
+		again, err := ParseEntry(got)
+		if err != nil || again != e {
+			t.Errorf("ParseEntry(%q) = %+v, %v; want %+v", got, again, err, e)
+		}
+	}
+}
+
+func TestAttendanceRoundTrip(t *testing.T) {
+	attendance := []string{"8", "W", "Y 4/2+1", "", "8+1.5"}
+	entries, err := ParseAttendance(attendance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatAttendance(entries); !reflect.DeepEqual(got, attendance) {
+		t.Errorf("FormatAttendance = %q, want %q", got, attendance)
+	}
+
+	_, err = ParseAttendance([]string{"8", "Y -1"})
+	if err == nil || !strings.HasPrefix(err.Error(), "day 2: ") {
+		t.Errorf("ParseAttendance error = %v, want a day 2 error", err)
+	}
+}
+
+func TestCodesValidateUnknownCode(t *testing.T) {
+	codes := DefaultCodes()
+	if err := codes.Validate([]string{"8", "Y 4", "ÝS", "B"}); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	err := codes.Validate([]string{"8", "W", "Q 8"})
+	if err == nil || err.Error() != `day 3: unknown attendance code "Q"` {
+		t.Errorf("Validate error = %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Employee field names used as LoadOptions.Header targets.
+const (
+	FieldID          = "Id"
+	FieldFullName    = "FullName"
+	FieldTableID     = "TableID"
+	FieldJobPosition = "JobPosition"
+	FieldAttendance  = "Attendance"
+)
+
+// defaultHeader maps lower-cased source headers to Employee fields.
+var defaultHeader = map[string]string{
+	"id":           FieldID,
+	"no":           FieldID,
+	"№":            FieldID,
+	"full_name":    FieldFullName,
+	"fullname":     FieldFullName,
+	"name":         FieldFullName,
+	"table_id":     FieldTableID,
+	"tableid":      FieldTableID,
+	"job_position": FieldJobPosition,
+	"jobposition":  FieldJobPosition,
+	"position":     FieldJobPosition,
+	"attendance":   FieldAttendance,
+}
+
+// LoadOptions configures how employees are read from a roster.
+//
+// Header maps source column headers (CSV/XLSX) or object keys (JSON) to
+// Employee fields (FieldID, FieldFullName, …). Matching is case-insensitive and
+// extends the built-in aliases ("id", "full_name", "name", "position", …).
+//
+// Columns and keys that map to no field are kept in Employee.Fields under
+// their original header.
+//
+// Attendance is read either from one Attendance column holding entries
+// separated by ";" or "," (a comma between digits is a decimal comma, so
+// "8; 7,5" is two entries), or from columns whose header is a day number
+// ("1", "2", …) in column order. In JSON, attendance is an array of strings
+// and numbers.
+//
+// When Period is set, every employee's attendance must have exactly
+// Period.Days() entries. Sheet selects the XLSX sheet (default: first sheet).
+type LoadOptions struct {
+	Header map[string]string
+	Period Period
+	Sheet  string
+}
+
+// LoadError reports an invalid roster record.
+// Line is the 1-based line (CSV), row (XLSX) or line of the object start (JSON).
+type LoadError struct {
+	Line  int
+	Field string
+	Err   error
+}
+
+func (e *LoadError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %v", e.Line, e.Field, e.Err)
+}
+
+func (e *LoadError) Unwrap() error { return e.Err }
+
+// LoadEmployees reads employees from a .csv, .json or .xlsx file,
+// chosen by the file extension.
+func LoadEmployees(path string, opts LoadOptions) ([]Employee, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open employees: %w", err)
+	}
+	defer file.Close()
+
+	var employees []Employee
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		employees, err = ReadEmployeesCSV(file, opts)
+	case ".json":
+		employees, err = ReadEmployeesJSON(file, opts)
+	case ".xlsx":
+		employees, err = ReadEmployeesXLSX(file, opts)
+	default:
+		return nil, fmt.Errorf("employees %s: unsupported format %q (want .csv, .json or .xlsx)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return employees, nil
+}
+
+// ReadEmployeesCSV reads employees from CSV with a header line.
+func ReadEmployeesCSV(r io.Reader, opts LoadOptions) ([]Employee, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	// The reader skips blank lines and a quoted field may span lines, so the
+	// line of each record comes from the reader rather than its index.
+	var (
+		rows  [][]string
+		lines []int
+	)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+
+	return readEmployeeTable(rows, lines, opts)
+}
+
+// ReadEmployeesXLSX reads employees from a workbook sheet whose first row is
+// the header.
+func ReadEmployeesXLSX(r io.Reader, opts LoadOptions) ([]Employee, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open xlsx: %w", err)
+	}
+	defer f.Close()
+
+	sheet := opts.Sheet
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("sheet %q: %w", sheet, err)
+	}
+
+	return readEmployeeTable(rows, nil, opts)
+}
+
+// ReadEmployeesJSON reads employees from a JSON array of objects.
+func ReadEmployeesJSON(r io.Reader, opts LoadOptions) ([]Employee, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read json: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("json: want an array of employee objects")
+	}
+
+	header := mergeHeader(opts.Header)
+
+	var employees []Employee
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+
+		var obj map[string]json.RawMessage
+		if err := dec.Decode(&obj); err != nil {
+			return nil, &LoadError{Line: line, Err: err}
+		}
+
+		fields := make(map[string]string)
+		extra := make(map[string]string)
+		var attendance []string
+		for key, raw := range obj {
+			field := header[strings.ToLower(strings.TrimSpace(key))]
+			if field == "" {
+				extra[strings.TrimSpace(key)] = jsonScalar(raw)
+				continue
+			}
+			if field == FieldAttendance {
+				var entries []json.RawMessage
+				if err := json.Unmarshal(raw, &entries); err != nil {
+					return nil, &LoadError{Line: line, Field: field, Err: errors.New("want an array of strings or numbers")}
+				}
+				attendance = make([]string, len(entries))
+				for i, raw := range entries {
+					entry, err := jsonEntry(raw)
+					if err != nil {
+						return nil, &LoadError{Line: line, Field: field, Err: fmt.Errorf("day %d: %w", i+1, err)}
+					}
+					attendance[i] = entry
+				}
+				continue
+			}
+			fields[field] = jsonScalar(raw)
+		}
+
+		emp, err := buildEmployee(fields, extra, attendance, len(employees), opts.Period)
+		if err != nil {
+			err.Line = line
+			return nil, err
+		}
+		employees = append(employees, emp)
+	}
+
+	return employees, nil
+}
+
+// readEmployeeTable converts header + data rows into employees. lines holds
+// the 1-based source line of each row; nil numbers the rows from 1.
+func readEmployeeTable(rows [][]string, lines []int, opts LoadOptions) ([]Employee, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("missing header line")
+	}
+
+	header := mergeHeader(opts.Header)
+
+	fieldCols := make(map[string]int)
+	extraCols := make(map[string]int)
+	var dayCols []int
+	for c, name := range rows[0] {
+		name = strings.TrimSpace(name)
+		if field, ok := header[strings.ToLower(name)]; ok {
+			fieldCols[field] = c
+			continue
+		}
+		if _, err := strconv.Atoi(name); err == nil {
+			dayCols = append(dayCols, c)
+			continue
+		}
+		if name != "" {
+			extraCols[name] = c
+		}
+	}
+
+	employees := make([]Employee, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		line := i + 2
+		if lines != nil {
+			line = lines[i+1]
+		}
+		if blankRow(row) {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for field, c := range fieldCols {
+			fields[field] = strings.TrimSpace(cellAt(row, c))
+		}
+		extra := make(map[string]string, len(extraCols))
+		for name, c := range extraCols {
+			extra[name] = strings.TrimSpace(cellAt(row, c))
+		}
+
+		var attendance []string
+		if text, ok := fields[FieldAttendance]; ok {
+			attendance = splitAttendance(text)
+		} else {
+			for _, c := range dayCols {
+				attendance = append(attendance, strings.TrimSpace(cellAt(row, c)))
+			}
+		}
+
+		emp, err := buildEmployee(fields, extra, attendance, len(employees), opts.Period)
+		if err != nil {
+			err.Line = line
+			return nil, err
+		}
+		employees = append(employees, emp)
+	}
+
+	return employees, nil
+}
+
+// buildEmployee validates one record. extra holds the values of unmapped
+// columns. index is the record's position among the employees read so far;
+// it numbers employees without an Id column.
+func buildEmployee(fields, extra map[string]string, attendance []string, index int, period Period) (Employee, *LoadError) {
+	emp := Employee{
+		Id:          index + 1,
+		FullName:    fields[FieldFullName],
+		TableID:     fields[FieldTableID],
+		JobPosition: fields[FieldJobPosition],
+		Attendance:  attendance,
+	}
+	if len(extra) > 0 {
+		emp.Fields = extra
+	}
+
+	if text := fields[FieldID]; text != "" {
+		id, err := strconv.Atoi(text)
+		if err != nil {
+			return Employee{}, &LoadError{Field: FieldID, Err: fmt.Errorf("invalid number %q", text)}
+		}
+		emp.Id = id
+	}
+
+	if !period.Start.IsZero() && len(attendance) != period.Days() {
+		return Employee{}, &LoadError{
+			Field: FieldAttendance,
+			Err:   fmt.Errorf("has %d entries, period %s has %d days", len(attendance), period, period.Days()),
+		}
+	}
+
+	return emp, nil
+}
+
+// mergeHeader returns the built-in aliases extended by custom, lower-cased.
+func mergeHeader(custom map[string]string) map[string]string {
+	header := make(map[string]string, len(defaultHeader)+len(custom))
+	for k, v := range defaultHeader {
+		header[k] = v
+	}
+	for k, v := range custom {
+		header[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return header
+}
+
+// splitAttendance splits an Attendance cell on ";" and on commas that are
+// not decimal commas: a comma between two digits, as in "7,5", belongs to
+// the entry.
+func splitAttendance(text string) []string {
+	if text == "" {
+		return nil
+	}
+	var parts []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case ',':
+			if i > 0 && i+1 < len(text) && isDigit(text[i-1]) && isDigit(text[i+1]) {
+				continue
+			}
+		case ';':
+		default:
+			continue
+		}
+		parts = append(parts, strings.TrimSpace(text[start:i]))
+		start = i + 1
+	}
+	return append(parts, strings.TrimSpace(text[start:]))
+}
+
+func isDigit(b byte) bool { return '0' <= b && b <= '9' }
+
+func cellAt(row []string, c int) string {
+	if c < len(row) {
+		return row[c]
+	}
+	return ""
+}
+
+func blankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonScalar renders a JSON string, number or bool as plain text.
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// jsonEntry renders one attendance entry, a JSON string or number, as text.
+func jsonEntry(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s), nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", fmt.Errorf("want a string or number, got %s", raw)
+	}
+	return n.String(), nil
+}
+
+// lineAt returns the 1-based line of the first non-space byte at or after offset.
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r' || data[i] == ',') {
+		i++
+	}
+	return bytes.Count(data[:i], []byte("\n")) + 1
+}
@@ -0,0 +1,306 @@
+package domain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// rosterXLSX writes rows to the first sheet of a new workbook.
+func rosterXLSX(t *testing.T, rows [][]any) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// wantEmployees is the roster every loader test reads.
+var wantEmployees = []Employee{
+	{Id: 7, FullName: "Aman Amanow", TableID: "T-1", JobPosition: "Hasapçy", Attendance: []string{"8", "W", "Y 4"}, Fields: map[string]string{"Department": "Sales"}},
+	{Id: 2, FullName: "Maral Myradowa", Attendance: []string{"8/2", "", "B"}, Fields: map[string]string{"Department": "IT"}},
+}
+
+func TestReadEmployeesCSV(t *testing.T) {
+	data := "id,full_name,table_id,position,Department,1,2,3\n" +
+		"7,Aman Amanow,T-1,Hasapçy,Sales,8,W,Y 4\n" +
+		"\n" +
+		"2,Maral Myradowa,,,IT,8/2,,B\n"
+
+	got, err := ReadEmployeesCSV(strings.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantEmployees) {
+		t.Errorf("employees =\n%+v\nwant\n%+v", got, wantEmployees)
+	}
+}
+
+func TestReadEmployeesCSVAttendanceColumn(t *testing.T) {
+	data := "Işgär,attendance\nAman Amanow,\"8, W, Y 4\"\n"
+
+	got, err := ReadEmployeesCSV(strings.NewReader(data), LoadOptions{Header: map[string]string{"Işgär": FieldFullName}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Employee{{Id: 1, FullName: "Aman Amanow", Attendance: []string{"8", "W", "Y 4"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("employees = %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitAttendance(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"8", []string{"8"}},
+		{"8, W, Y 4", []string{"8", "W", "Y 4"}},
+		{"8; W; Y 4", []string{"8", "W", "Y 4"}},
+		{"7,5", []string{"7,5"}},
+		{"7,5, W,8", []string{"7,5", "W", "8"}},
+		{"7,5;8,25", []string{"7,5", "8,25"}},
+		{"8/2,5,Y 4", []string{"8/2,5", "Y 4"}},
+		{"8,,W", []string{"8", "", "W"}},
+		{"W,8", []string{"W", "8"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitAttendance(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitAttendance(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadEmployeesJSONNumericAttendance(t *testing.T) {
+	data := `[{"name": "Aman", "attendance": [8, "W", 7.5, "Y 4", null]}]`
+
+	got, err := ReadEmployeesJSON(strings.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Employee{{Id: 1, FullName: "Aman", Attendance: []string{"8", "W", "7.5", "Y 4", ""}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("employees = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadEmployeesJSON(t *testing.T) {
+	data := `[
+  {"id": 7, "full_name": "Aman Amanow", "table_id": "T-1", "position": "Hasapçy", "Department": "Sales", "attendance": ["8", "W", "Y 4"]},
+  {"id": "2", "name": "Maral Myradowa", "Department": "IT", "attendance": ["8/2", "", "B"]}
+]`
+
+	got, err := ReadEmployeesJSON(strings.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantEmployees) {
+		t.Errorf("employees =\n%+v\nwant\n%+v", got, wantEmployees)
+	}
+}
+
+func TestReadEmployeesXLSX(t *testing.T) {
+	data := rosterXLSX(t, [][]any{
+		{"ID", "Full_Name", "TableID", "JobPosition", "Department", "1", "2", "3"},
+		{7, "Aman Amanow", "T-1", "Hasapçy", "Sales", "8", "W", "Y 4"},
+		{},
+		{2, "Maral Myradowa", "", "", "IT", "8/2", "", "B"},
+	})
+
+	got, err := ReadEmployeesXLSX(strings.NewReader(string(data)), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, wantEmployees) {
+		t.Errorf("employees =\n%+v\nwant\n%+v", got, wantEmployees)
+	}
+}
+
+func TestReadEmployeesErrors(t *testing.T) {
+	period := MonthPeriod(2026, time.February)
+
+	tests := []struct {
+		name string
+		read func() ([]Employee, error)
+		line int    // LoadError line, 0 when the error is not a *LoadError
+		want string // error text
+	}{
+		{
+			name: "csv bad id",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader("id,name\n1,Aman\nx,Maral\n"), LoadOptions{})
+			},
+			line: 3,
+			want: `line 3: Id: invalid number "x"`,
+		},
+		{
+			name: "csv line after blank lines",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader("id,name\n\n\n1,Aman\n\nx,Maral\n"), LoadOptions{})
+			},
+			line: 6,
+			want: `line 6: Id: invalid number "x"`,
+		},
+		{
+			name: "csv line after a multi-line field",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader("id,name\n1,\"Aman\nAmanow\"\nx,Maral\n"), LoadOptions{})
+			},
+			line: 4,
+			want: `line 4: Id: invalid number "x"`,
+		},
+		{
+			name: "csv attendance length",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader("name,attendance\nAman,\"8; 7,5\"\n"), LoadOptions{Period: period})
+			},
+			line: 2,
+			want: "line 2: Attendance: has 2 entries, period 2026-02 has 28 days",
+		},
+		{
+			name: "csv syntax",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader("id,name\n1,\"Aman\n"), LoadOptions{})
+			},
+			want: "read csv:",
+		},
+		{
+			name: "csv empty",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesCSV(strings.NewReader(""), LoadOptions{})
+			},
+			want: "missing header line",
+		},
+		{
+			name: "json not an array",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesJSON(strings.NewReader(`{"id": 1}`), LoadOptions{})
+			},
+			want: "json: want an array of employee objects",
+		},
+		{
+			name: "json bad id",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesJSON(strings.NewReader("[\n  {\"id\": 1},\n  {\"id\": \"x\"}\n]"), LoadOptions{})
+			},
+			line: 3,
+			want: `line 3: Id: invalid number "x"`,
+		},
+		{
+			name: "json bad attendance",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesJSON(strings.NewReader(`[{"attendance": "8,8"}]`), LoadOptions{})
+			},
+			line: 1,
+			want: "line 1: Attendance: want an array of strings or numbers",
+		},
+		{
+			name: "json bad attendance entry",
+			read: func() ([]Employee, error) {
+				return ReadEmployeesJSON(strings.NewReader(`[{"attendance": ["8", true]}]`), LoadOptions{})
+			},
+			line: 1,
+			want: "line 1: Attendance: day 2: want a string or number, got true",
+		},
+		{
+			name: "xlsx bad id",
+			read: func() ([]Employee, error) {
+				data := rosterXLSX(t, [][]any{{"id", "name"}, {1, "Aman"}, {}, {"x", "Maral"}})
+				return ReadEmployeesXLSX(strings.NewReader(string(data)), LoadOptions{})
+			},
+			line: 4,
+			want: `line 4: Id: invalid number "x"`,
+		},
+		{
+			name: "xlsx missing sheet",
+			read: func() ([]Employee, error) {
+				data := rosterXLSX(t, [][]any{{"id"}})
+				return ReadEmployeesXLSX(strings.NewReader(string(data)), LoadOptions{Sheet: "Roster"})
+			},
+			want: `sheet "Roster":`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.read()
+			if err == nil {
+				t.Fatalf("want an error %q", tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+
+			var lerr *LoadError
+			if errors.As(err, &lerr) != (tt.line != 0) {
+				t.Fatalf("error %q: *LoadError = %v, want line %d", err, lerr, tt.line)
+			}
+			if lerr != nil && lerr.Line != tt.line {
+				t.Errorf("line = %d, want %d", lerr.Line, tt.line)
+			}
+		})
+	}
+}
+
+func TestLoadEmployees(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"roster.csv":  []byte("id,name\n1,Aman\n"),
+		"roster.JSON": []byte(`[{"id": 1, "name": "Aman"}]`),
+		"roster.xlsx": rosterXLSX(t, [][]any{{"id", "name"}, {1, "Aman"}}),
+	}
+	want := []Employee{{Id: 1, FullName: "Aman"}}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadEmployees(path, LoadOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: employees = %+v, want %+v", name, got, want)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.csv")
+	if err := os.WriteFile(bad, []byte("id\nx\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEmployees(bad, LoadOptions{}); err == nil || err.Error() != bad+`: line 2: Id: invalid number "x"` {
+		t.Errorf("bad roster error = %v", err)
+	}
+
+	if _, err := LoadEmployees(filepath.Join(dir, "roster.txt"), LoadOptions{}); err == nil || !strings.Contains(err.Error(), "open employees") {
+		t.Errorf("missing file error = %v", err)
+	}
+
+	txt := filepath.Join(dir, "roster.txt")
+	if err := os.WriteFile(txt, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEmployees(txt, LoadOptions{}); err == nil || !strings.Contains(err.Error(), `unsupported format ".txt"`) {
+		t.Errorf("unsupported format error = %v", err)
+	}
+}
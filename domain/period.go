@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Period is the reporting range a timesheet covers.
+// Start and End are inclusive calendar dates (time of day is ignored).
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MonthPeriod returns the period covering the whole given month.
+func MonthPeriod(year int, month time.Month) Period {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	return Period{Start: start, End: start.AddDate(0, 1, -1)}
+}
+
+// CurrentPeriod returns the period covering the current local month.
+func CurrentPeriod() Period {
+	year, month, _ := time.Now().Local().Date()
+	return MonthPeriod(year, month)
+}
+
+// NewPeriod returns the period from start to end inclusive.
+// It fails when end is before start.
+func NewPeriod(start, end time.Time) (Period, error) {
+	start, end = dateOnly(start), dateOnly(end)
+	if end.Before(start) {
+		return Period{}, fmt.Errorf("period end %s is before start %s",
+			end.Format(time.DateOnly), start.Format(time.DateOnly))
+	}
+	return Period{Start: start, End: end}, nil
+}
+
+// ParsePeriod parses a month ("2026-09") or an explicit date range
+// ("2026-09-01..2026-09-15").
+func ParsePeriod(s string) (Period, error) {
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		start, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(from), time.Local)
+		if err != nil {
+			return Period{}, fmt.Errorf("parse period start: %w", err)
+		}
+		end, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(to), time.Local)
+		if err != nil {
+			return Period{}, fmt.Errorf("parse period end: %w", err)
+		}
+		return NewPeriod(start, end)
+	}
+
+	month, err := time.ParseInLocation("2006-01", strings.TrimSpace(s), time.Local)
+	if err != nil {
+		return Period{}, fmt.Errorf("parse period %q: want YYYY-MM or YYYY-MM-DD..YYYY-MM-DD", s)
+	}
+	return MonthPeriod(month.Year(), month.Month()), nil
+}
+
+// Days returns the number of calendar days in the period.
+func (p Period) Days() int {
+	return int(dateOnly(p.End).Sub(dateOnly(p.Start)).Hours()/24+0.5) + 1
+}
+
+// Day returns the date of the i-th day of the period (0-based).
+func (p Period) Day(i int) time.Time {
+	return dateOnly(p.Start).AddDate(0, 0, i)
+}
+
+// String formats the period as accepted by ParsePeriod.
+func (p Period) String() string {
+	if p.Start.Day() == 1 && p.End.Equal(p.Start.AddDate(0, 1, -1)) {
+		return p.Start.Format("2006-01")
+	}
+	return p.Start.Format(time.DateOnly) + ".." + p.End.Format(time.DateOnly)
+}
+
+func dateOnly(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
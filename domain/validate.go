@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IssueKind classifies a validation issue.
+type IssueKind string
+
+const (
+	IssueUnknownCode      IssueKind = "unknown_code"       // attendance code not in Codes
+	IssueInvalidEntry     IssueKind = "invalid_entry"      // attendance text ParseEntry rejects
+	IssueLength           IssueKind = "length"             // attendance length differs from the period
+	IssueDuplicateTableID IssueKind = "duplicate_table_id" // TableID used by an earlier employee
+	IssueMissingName      IssueKind = "missing_name"       // empty FullName
+)
+
+// Issue is one problem found by ValidateEmployees.
+//
+// Employee is the 0-based index of the employee in the validated list. Day is
+// the 1-based attendance day for issues about one attendance cell and 0
+// otherwise; Field then names the employee field concerned.
+type Issue struct {
+	Kind     IssueKind
+	Employee int
+	Name     string // the employee's FullName, for messages
+	Day      int
+	Field    string
+	Value    string
+	Message  string
+}
+
+func (i Issue) String() string {
+	who := fmt.Sprintf("employee %d", i.Employee+1)
+	if i.Name != "" {
+		who += " (" + i.Name + ")"
+	}
+	if i.Day > 0 {
+		return fmt.Sprintf("%s: day %d: %s", who, i.Day, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", who, i.Message)
+}
+
+// Report lists the issues of a roster, in employee order.
+type Report struct {
+	Issues []Issue
+}
+
+// OK reports whether no issue was found.
+func (r *Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns nil for a clean report, or an error listing every issue.
+func (r *Report) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return fmt.Errorf("%d validation issue(s):\n%s", len(r.Issues), r)
+}
+
+// ForEmployee returns the issues of the employee at index i.
+func (r *Report) ForEmployee(i int) []Issue {
+	var issues []Issue
+	for _, issue := range r.Issues {
+		if issue.Employee == i {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// String renders one issue per line.
+func (r *Report) String() string {
+	lines := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateOptions configures ValidateEmployees.
+//
+// Codes, when set, lists the known attendance codes; without it only the
+// entry syntax is checked. Period, when set, fixes the attendance length.
+type ValidateOptions struct {
+	Codes  *Codes
+	Period Period
+}
+
+// ValidateEmployees checks a roster before it is written: attendance entries
+// and codes, attendance length, duplicate TableIDs and missing names.
+func ValidateEmployees(employees []Employee, opts ValidateOptions) *Report {
+	r := &Report{}
+	tableIDs := make(map[string]int)
+
+	for i, emp := range employees {
+		issue := func(kind IssueKind, day int, field, value, format string, args ...any) {
+			r.Issues = append(r.Issues, Issue{
+				Kind:     kind,
+				Employee: i,
+				Name:     emp.FullName,
+				Day:      day,
+				Field:    field,
+				Value:    value,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if strings.TrimSpace(emp.FullName) == "" {
+			issue(IssueMissingName, 0, FieldFullName, "", "missing name")
+		}
+
+		if emp.TableID != "" {
+			if first, ok := tableIDs[emp.TableID]; ok {
+				issue(IssueDuplicateTableID, 0, FieldTableID, emp.TableID,
+					"table ID %q already used by employee %d", emp.TableID, first+1)
+			} else {
+				tableIDs[emp.TableID] = i
+			}
+		}
+
+		if !opts.Period.Start.IsZero() && len(emp.Attendance) != opts.Period.Days() {
+			issue(IssueLength, 0, FieldAttendance, "",
+				"attendance has %d entries, period %s has %d days", len(emp.Attendance), opts.Period, opts.Period.Days())
+		}
+
+		for d, s := range emp.Attendance {
+			e, err := ParseEntry(s)
+			if err != nil {
+				issue(IssueInvalidEntry, d+1, FieldAttendance, s, "%v", err)
+				continue
+			}
+			if e.Code == "" || opts.Codes == nil {
+				continue
+			}
+			if _, ok := opts.Codes.Lookup(e.Code); !ok {
+				issue(IssueUnknownCode, d+1, FieldAttendance, s, "unknown attendance code %q", e.Code)
+			}
+		}
+	}
+
+	return r
+}
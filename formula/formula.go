@@ -0,0 +1,337 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is an Excel formula expression. Build one with the functions of this
+// package and turn it into formula text with Render.
+type Expr interface {
+	// write appends the formula text of the expression to b.
+	write(b *strings.Builder) error
+	// prec is the operator precedence of the expression; operands that bind
+	// less tightly than their operator are parenthesized.
+	prec() int
+}
+
+// Operator precedences, loosest first. Functions, references and literals
+// are atoms and never need parentheses.
+const (
+	precCompare = iota + 1
+	precConcat
+	precAdd
+	precMul
+	precAtom
+)
+
+// Render returns the Excel formula text of e, without the leading "=".
+// It reports invalid references, literals and argument counts.
+func Render(e Expr) (string, error) {
+	if e == nil {
+		return "", fmt.Errorf("formula: nil expression")
+	}
+	var b strings.Builder
+	if err := e.write(&b); err != nil {
+		return "", fmt.Errorf("formula: %w", err)
+	}
+	return b.String(), nil
+}
+
+// MustRender is like Render but panics on an invalid expression. It is meant
+// for expressions built from constants.
+func MustRender(e Expr) string {
+	s, err := Render(e)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ---------- References ----------
+
+// cellPat matches an A1 cell reference with optional $ anchors.
+var cellPat = regexp.MustCompile(`^\$?[A-Za-z]{1,3}\$?[1-9][0-9]*$`)
+
+type ref struct {
+	sheet string
+	from  string
+	to    string // "" for a single cell
+}
+
+// Ref is a reference to one cell, e.g. Ref("E5") or Ref("$A$1").
+func Ref(cell string) Expr {
+	return ref{from: cell}
+}
+
+// Range is a reference to a cell range, e.g. Range("E5", "AF5") → E5:AF5.
+func Range(from, to string) Expr {
+	return ref{from: from, to: to}
+}
+
+// ParseRange turns "E5:AF5" or a single "E5" into a reference.
+func ParseRange(s string) Expr {
+	from, to, _ := strings.Cut(s, ":")
+	return ref{from: from, to: to}
+}
+
+// On qualifies a reference made by Ref, Range or ParseRange with a sheet
+// name, quoting it when needed: On("Jan 2026", Range("A1", "B2")) →
+// 'Jan 2026'!A1:B2. Other expressions are returned unchanged.
+func On(sheet string, e Expr) Expr {
+	if r, ok := e.(ref); ok {
+		r.sheet = sheet
+		return r
+	}
+	return e
+}
+
+// plainSheetPat matches sheet names that need no quotes.
+var plainSheetPat = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+func (r ref) write(b *strings.Builder) error {
+	for _, c := range []string{r.from, r.to} {
+		if c != "" && !cellPat.MatchString(c) {
+			return fmt.Errorf("invalid cell reference %q", c)
+		}
+	}
+	if r.from == "" {
+		return fmt.Errorf("empty cell reference")
+	}
+	if r.sheet != "" {
+		if strings.ContainsAny(r.sheet, `[]:*?/\`) {
+			return fmt.Errorf("invalid sheet name %q", r.sheet)
+		}
+		if plainSheetPat.MatchString(r.sheet) {
+			b.WriteString(r.sheet)
+		} else {
+			b.WriteString("'" + strings.ReplaceAll(r.sheet, "'", "''") + "'")
+		}
+		b.WriteByte('!')
+	}
+	b.WriteString(strings.ToUpper(r.from))
+	if r.to != "" {
+		b.WriteString(":" + strings.ToUpper(r.to))
+	}
+	return nil
+}
+
+func (ref) prec() int { return precAtom }
+
+// ---------- Literals ----------
+
+// maxStrLen is the longest string literal Excel accepts in a formula.
+const maxStrLen = 255
+
+type str string
+
+// Str is a string literal. Double quotes are escaped as Excel expects:
+// Str(`say "hi"`) → "say ""hi""".
+func Str(s string) Expr {
+	return str(s)
+}
+
+func (s str) write(b *strings.Builder) error {
+	if len([]rune(string(s))) > maxStrLen {
+		return fmt.Errorf("string literal longer than %d characters", maxStrLen)
+	}
+	b.WriteString(`"` + strings.ReplaceAll(string(s), `"`, `""`) + `"`)
+	return nil
+}
+
+func (str) prec() int { return precAtom }
+
+type num float64
+
+// Num is a numeric literal.
+func Num(v float64) Expr {
+	return num(v)
+}
+
+// Int is an integer literal.
+func Int(v int) Expr {
+	return num(float64(v))
+}
+
+func (n num) write(b *strings.Builder) error {
+	v := float64(n)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("invalid number %v", v)
+	}
+	if v < 0 {
+		// A negative literal is an operand of unary minus; keep it atomic.
+		b.WriteString("(" + strconv.FormatFloat(v, 'f', -1, 64) + ")")
+		return nil
+	}
+	b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	return nil
+}
+
+func (num) prec() int { return precAtom }
+
+// ---------- Operators ----------
+
+type binary struct {
+	op       string
+	operands []Expr
+	p        int
+	assoc    bool // a op (b op c) == (a op b) op c
+}
+
+// Eq compares a and b: Eq(Range("E5", "AF5"), Str("W")) → E5:AF5="W".
+func Eq(a, b Expr) Expr {
+	return binary{op: "=", operands: []Expr{a, b}, p: precCompare}
+}
+
+// Ne is true when a and b differ: Ne(r, Str("")) → r<>"".
+func Ne(a, b Expr) Expr {
+	return binary{op: "<>", operands: []Expr{a, b}, p: precCompare}
+}
+
+// Concat joins its operands as text: Concat(r, Str("/")) → r&"/".
+func Concat(operands ...Expr) Expr {
+	return binary{op: "&", operands: operands, p: precConcat, assoc: true}
+}
+
+// Add adds its operands: Add(a, b, c) → a+b+c.
+func Add(operands ...Expr) Expr {
+	return binary{op: "+", operands: operands, p: precAdd, assoc: true}
+}
+
+// Sub subtracts the other operands from the first: Sub(a, b, c) → a-b-c.
+func Sub(operands ...Expr) Expr {
+	return binary{op: "-", operands: operands, p: precAdd}
+}
+
+// Mul multiplies its operands: Mul(Eq(r, Str("T")), Int(8)) → (r="T")*8.
+func Mul(operands ...Expr) Expr {
+	return binary{op: "*", operands: operands, p: precMul, assoc: true}
+}
+
+func (e binary) write(b *strings.Builder) error {
+	if len(e.operands) < 2 {
+		return fmt.Errorf("operator %s: want at least 2 operands, got %d", e.op, len(e.operands))
+	}
+	for i, operand := range e.operands {
+		if operand == nil {
+			return fmt.Errorf("operator %s: operand %d is nil", e.op, i+1)
+		}
+		if i > 0 {
+			b.WriteString(e.op)
+		}
+		// Right operands of a non-associative operator (comparison,
+		// subtraction) are parenthesized even at equal precedence.
+		paren := operand.prec() < e.p || (operand.prec() == e.p && !e.assoc && i > 0)
+		if paren {
+			b.WriteByte('(')
+		}
+		if err := operand.write(b); err != nil {
+			return err
+		}
+		if paren {
+			b.WriteByte(')')
+		}
+	}
+	return nil
+}
+
+func (e binary) prec() int { return e.p }
+
+// ---------- Functions ----------
+
+type call struct {
+	name string
+	args []Expr
+}
+
+// arity is the accepted argument count of a known function.
+type arity struct{ min, max int }
+
+// arities lists the functions with dedicated builders. Call checks other
+// names only for their syntax.
+var arities = map[string]arity{
+	"COUNTIF":    {2, 2},
+	"SUM":        {1, 255},
+	"SUMPRODUCT": {1, 255},
+	"IF":         {2, 3},
+	"IFERROR":    {2, 2},
+	"VALUE":      {1, 1},
+	"SUBTOTAL":   {2, 255},
+}
+
+// funcNamePat matches Excel function names such as SUM or COUNTIFS.
+var funcNamePat = regexp.MustCompile(`^[A-Z][A-Z0-9.]*$`)
+
+// Call calls the Excel function name with args. Prefer the dedicated builders
+// (Sum, CountIf, If …); Call covers the rest.
+func Call(name string, args ...Expr) Expr {
+	return call{name: strings.ToUpper(name), args: args}
+}
+
+// CountIf counts the cells of rng that meet criteria: COUNTIF(rng,criteria).
+func CountIf(rng, criteria Expr) Expr {
+	return Call("COUNTIF", rng, criteria)
+}
+
+// Sum adds its arguments: SUM(args…).
+func Sum(args ...Expr) Expr {
+	return Call("SUM", args...)
+}
+
+// SumProduct is SUMPRODUCT(args…); with one array argument it sums the
+// array, which makes it the usual way to count with a condition.
+func SumProduct(args ...Expr) Expr {
+	return Call("SUMPRODUCT", args...)
+}
+
+// If is IF(cond,then,otherwise).
+func If(cond, then, otherwise Expr) Expr {
+	return Call("IF", cond, then, otherwise)
+}
+
+// IfError is IFERROR(value,fallback).
+func IfError(value, fallback Expr) Expr {
+	return Call("IFERROR", value, fallback)
+}
+
+// Value converts text to a number: VALUE(text).
+func Value(text Expr) Expr {
+	return Call("VALUE", text)
+}
+
+// Subtotal is SUBTOTAL(function,refs…), e.g. Subtotal(9, r) sums r while
+// skipping other SUBTOTAL cells.
+func Subtotal(function int, refs ...Expr) Expr {
+	return Call("SUBTOTAL", append([]Expr{Int(function)}, refs...)...)
+}
+
+func (c call) write(b *strings.Builder) error {
+	if !funcNamePat.MatchString(c.name) {
+		return fmt.Errorf("invalid function name %q", c.name)
+	}
+	if a, ok := arities[c.name]; ok && (len(c.args) < a.min || len(c.args) > a.max) {
+		if a.min == a.max {
+			return fmt.Errorf("%s: want %d arguments, got %d", c.name, a.min, len(c.args))
+		}
+		return fmt.Errorf("%s: want %d to %d arguments, got %d", c.name, a.min, a.max, len(c.args))
+	}
+	b.WriteString(c.name + "(")
+	for i, arg := range c.args {
+		if arg == nil {
+			return fmt.Errorf("%s: argument %d is nil", c.name, i+1)
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := arg.write(b); err != nil {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	b.WriteByte(')')
+	return nil
+}
+
+func (call) prec() int { return precAtom }
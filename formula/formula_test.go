@@ -0,0 +1,153 @@
+package formula
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	r := Range("E5", "AF5")
+
+	tests := []struct {
+		name string
+		expr Expr
+		want string
+	}{
+		{"cell", Ref("e5"), "E5"},
+		{"anchored cell", Ref("$A$1"), "$A$1"},
+		{"range", r, "E5:AF5"},
+		{"parsed range", ParseRange("E5:AF5"), "E5:AF5"},
+		{"parsed cell", ParseRange("E5"), "E5"},
+		{"plain sheet", On("Sheet1", r), "Sheet1!E5:AF5"},
+		{"quoted sheet", On("Jan 2026", r), "'Jan 2026'!E5:AF5"},
+		{"sheet with quote", On("Aman's", Ref("A1")), "'Aman''s'!A1"},
+		{"On ignores non-references", On("Sheet1", Int(1)), "1"},
+
+		{"string", Str("W"), `"W"`},
+		{"empty string", Str(""), `""`},
+		{"string with quotes", Str(`say "hi"`), `"say ""hi"""`},
+		{"unicode string", Str("ÝS"), `"ÝS"`},
+		{"int", Int(8), "8"},
+		{"float", Num(2.5), "2.5"},
+		{"negative", Num(-1.5), "(-1.5)"},
+
+		{"eq", Eq(r, Str("W")), `E5:AF5="W"`},
+		{"ne", Ne(r, Str("")), `E5:AF5<>""`},
+		{"concat", Concat(r, Str("/")), `E5:AF5&"/"`},
+		{"add", Add(Int(1), Int(2), Int(3)), "1+2+3"},
+		{"sub", Sub(Int(1), Int(2), Int(3)), "1-2-3"},
+		{"mul", Mul(Eq(r, Str("T")), Int(8)), `(E5:AF5="T")*8`},
+
+		{"countif", CountIf(r, Str("W")), `COUNTIF(E5:AF5,"W")`},
+		{"sum", Sum(Ref("A1"), Ref("B1")), "SUM(A1,B1)"},
+		{"sumproduct", SumProduct(Mul(Eq(r, Str("W")), Int(1))), `SUMPRODUCT((E5:AF5="W")*1)`},
+		{"if", If(Eq(Ref("A1"), Int(0)), Str(""), Ref("A1")), `IF(A1=0,"",A1)`},
+		{"iferror", IfError(Value(Ref("A1")), Int(0)), "IFERROR(VALUE(A1),0)"},
+		{"subtotal", Subtotal(9, Range("F5", "F9")), "SUBTOTAL(9,F5:F9)"},
+		{"call", Call("round", Ref("A1"), Int(1)), "ROUND(A1,1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.expr)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPrecedence(t *testing.T) {
+	a, b, c := Ref("A1"), Ref("B1"), Ref("C1")
+
+	tests := []struct {
+		name string
+		expr Expr
+		want string
+	}{
+		{"mul of sums", Mul(Add(a, b), c), "(A1+B1)*C1"},
+		{"sum of products", Add(Mul(a, b), c), "A1*B1+C1"},
+		{"nested add is flat", Add(a, Add(b, c)), "A1+B1+C1"},
+		{"nested mul is flat", Mul(Mul(a, b), c), "A1*B1*C1"},
+		{"sub keeps right grouping", Sub(a, Sub(b, c)), "A1-(B1-C1)"},
+		{"sub of sum on the right", Sub(a, Add(b, c)), "A1-(B1+C1)"},
+		{"sub of sum on the left", Sub(Add(a, b), c), "A1+B1-C1"},
+		{"add of sub", Add(a, Sub(b, c)), "A1+B1-C1"},
+		{"compare of sums", Eq(Add(a, b), c), "A1+B1=C1"},
+		{"compare on the right", Eq(a, Eq(b, c)), "A1=(B1=C1)"},
+		{"concat binds looser than add", Concat(Add(a, b), Str("h")), `A1+B1&"h"`},
+		{"add of concat", Add(Concat(a, b), c), "(A1&B1)+C1"},
+		{"mul of compare", Mul(Eq(a, Str("W")), Eq(b, Str("Y"))), `(A1="W")*(B1="Y")`},
+		{"function args need no parens", Sum(Add(a, b), Mul(b, c)), "SUM(A1+B1,B1*C1)"},
+		{"function as operand", Mul(Sum(a, b), c), "SUM(A1,B1)*C1"},
+		{"negative operand", Sub(a, Int(-2)), "A1-(-2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.expr)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+		want string // substring of the error
+	}{
+		{"nil", nil, "nil expression"},
+		{"bad cell", Ref("A0"), `invalid cell reference "A0"`},
+		{"bad range end", Range("A1", "B"), `invalid cell reference "B"`},
+		{"empty reference", ParseRange(""), "empty cell reference"},
+		{"bad sheet", On("a/b", Ref("A1")), `invalid sheet name "a/b"`},
+		{"long string", Str(strings.Repeat("x", 256)), "longer than 255"},
+		{"NaN", Num(math.NaN()), "invalid number"},
+		{"infinity", Num(math.Inf(1)), "invalid number"},
+		{"one operand", Add(Int(1)), "want at least 2 operands"},
+		{"nil operand", Mul(Int(1), nil), "operand 2 is nil"},
+		{"bad function name", Call("SUM X", Int(1)), `invalid function name "SUM X"`},
+		{"too few arguments", Call("IFERROR", Int(1)), "IFERROR: want 2 arguments, got 1"},
+		{"too many arguments", Call("IF", Int(1), Int(2), Int(3), Int(4)), "IF: want 2 to 3 arguments, got 4"},
+		{"nil argument", Sum(Int(1), nil), "argument 2 is nil"},
+		{"nested error names the function", Sum(Value(Ref("A0"))), "SUM: VALUE: invalid cell reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(tt.expr)
+			if err == nil {
+				t.Fatalf("Render: want an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Render error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringLiteralLimitCountsCharacters(t *testing.T) {
+	// 255 two-byte characters are within the limit.
+	if _, err := Render(Str(strings.Repeat("ý", 255))); err != nil {
+		t.Errorf("Render of 255 characters: %v", err)
+	}
+}
+
+func TestMustRenderPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustRender of an invalid expression did not panic")
+		}
+	}()
+	MustRender(Ref("A0"))
+}
@@ -5,6 +5,7 @@ go 1.25.7
 require (
 	github.com/bxcodec/faker/v4 v4.0.0-beta.3
 	github.com/xuri/excelize/v2 v2.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
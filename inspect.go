@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/orayew2002/rast-excel/job"
+	"github.com/orayew2002/rast-excel/processor"
+)
+
+// runInspect implements `rast-excel inspect [-format text|json] template.xlsx`:
+// it lists the placeholders the template uses without writing anything.
+func runInspect(args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	configPath := fs.String("config", "", "path to a YAML job file whose stages are inspected")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rast-excel inspect [-config job.yaml] [-format text|json] template.xlsx")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	pipeline, err := configPipeline(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	in, err := pipeline.InspectFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inspect: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		printInspection(os.Stdout, in)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(in); err != nil {
+			fmt.Fprintf(os.Stderr, "inspect: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "format: unknown format %q (want text or json)\n", *format)
+		return 2
+	}
+
+	return 0
+}
+
+// configPipeline builds the stages of the job at path (the default job when
+// empty). Handlers do not run during inspection, so no roster is loaded.
+func configPipeline(path string) (*processor.Pipeline, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	j, err := job.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return j.Pipeline()
+}
+
+// printInspection writes in as two tables: matched cells and unhandled text.
+func printInspection(w io.Writer, in *processor.Inspection) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tSHEET\tCELL\tMATCH\tPATTERN\tHANDLER")
+	for _, m := range in.Matches {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", m.Stage, m.Sheet, m.Cell, m.Match, m.Pattern, m.Handler)
+	}
+	tw.Flush()
+
+	if len(in.Unhandled) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%d unhandled placeholder(s):\n", len(in.Unhandled))
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SHEET\tCELL\tPLACEHOLDER")
+	for _, u := range in.Unhandled {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", u.Sheet, u.Cell, u.Match)
+	}
+	tw.Flush()
+}
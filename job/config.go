@@ -0,0 +1,147 @@
+package job
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Stage names, in the order a job runs them.
+const (
+	StageStructure = "structure" // {{days}}, {{working_time}}, {{start_process}}, {{marks_list}}, replace pairs
+	StageFormulas  = "formulas"  // formula keys below the employee blocks, {{total}}
+	StageMerge     = "merge"     // [r:c] and {{merge r c}} codes
+	StageBorder    = "border"    // &1 … &1 borders
+)
+
+// DefaultStages lists every stage, in run order.
+var DefaultStages = []string{StageStructure, StageFormulas, StageMerge, StageBorder}
+
+// Formula key types of FormulaKey.Type.
+const (
+	KeyCount       = "count"        // CountIFExpr(Symbol, Weight)
+	KeySumNum      = "sum_num"      // SumNumExpr
+	KeyCountNum    = "count_num"    // CountNumExpr
+	KeyHours       = "hours"        // HoursExpr(Symbol)
+	KeyNightHours  = "night_hours"  // NightHoursExpr(Symbol)
+	KeyOvertime    = "overtime"     // OvertimeExpr(Symbol)
+	KeyHourEntries = "hour_entries" // HourEntriesExpr(Symbol)
+	KeyStyle       = "style"        // style-only key, no formula
+	KeyCodes       = "codes"        // every key generated from the attendance codes
+)
+
+// Config is a declarative render job, usually read from a YAML file:
+//
+//	input: table.xlsx
+//	output: result.xlsx
+//	period: 2026-03
+//	holidays: holidays.txt
+//	days: {weekdays: true, locale: ru, weekend_fill: FFE699}
+//	employees: {path: roster.csv}
+//	validate: highlight
+//	eval: cached
+//	marks:
+//	  - {name: Işe çykdy, key: "8"}
+//	formula_keys:
+//	  - {type: codes}
+//	  - {key: "{{eight}}", type: count, symbol: "8", weight: 8}
+//	replace:
+//	  - {key: "{{year}}", value: "2026"}
+//	stages: [structure, formulas, merge, border]
+//
+// Empty fields take the CLI defaults: the current month, random employees,
+// the legend of the default attendance codes, the default formula keys and
+// every stage.
+type Config struct {
+	Input     string       `yaml:"input"`
+	Output    string       `yaml:"output"`
+	Period    string       `yaml:"period"`
+	Holidays  string       `yaml:"holidays"`
+	Days      Days         `yaml:"days"`
+	Employees Employees    `yaml:"employees"`
+	Validate  string       `yaml:"validate"`
+	Eval      string       `yaml:"eval"`
+	KeepGoing bool         `yaml:"keep_going"`
+	Marks     []Mark       `yaml:"marks"`
+	Keys      []FormulaKey `yaml:"formula_keys"`
+	Replace   []Replace    `yaml:"replace"`
+	Stages    []string     `yaml:"stages"`
+}
+
+// Days configures the {{days}} header (see template.DaysOptions).
+type Days struct {
+	Weekdays    bool   `yaml:"weekdays"`
+	Locale      string `yaml:"locale"`
+	WeekendFill string `yaml:"weekend_fill"`
+}
+
+// Employees selects the roster: a CSV, JSON or XLSX file, or Generate random
+// employees when Path is empty.
+type Employees struct {
+	Path     string `yaml:"path"`
+	Generate int    `yaml:"generate"`
+}
+
+// Mark is one entry of the {{marks_list}} legend (see domain.Mark).
+type Mark struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// FormulaKey declares one formula key of the formulas stage. Symbol is the
+// attendance symbol counted by count keys, or the code whose hours the hour
+// keys sum ("" for all entries); Weight multiplies count keys (default 1).
+type FormulaKey struct {
+	Key    string `yaml:"key"`
+	Type   string `yaml:"type"`
+	Symbol string `yaml:"symbol"`
+	Weight int    `yaml:"weight"`
+}
+
+// Replace is one key → value pair of the structure stage.
+type Replace struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+// Load reads a YAML job file. Relative paths in it are resolved against the
+// file's directory.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.resolve(filepath.Dir(path))
+	return c, nil
+}
+
+// Parse decodes a YAML job. Unknown fields are an error; an empty document is
+// the default job.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse job: %w", err)
+	}
+	return &c, nil
+}
+
+// resolve makes the file paths of c relative to dir.
+func (c *Config) resolve(dir string) {
+	for _, p := range []*string{&c.Input, &c.Output, &c.Holidays, &c.Employees.Path} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package job
+
+import (
+	"fmt"
+
+	"github.com/orayew2002/rast-excel/calendar"
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/processor"
+	"github.com/orayew2002/rast-excel/template"
+)
+
+// DefaultEmployeeCount is the number of random employees generated when a job
+// names no roster.
+const DefaultEmployeeCount = 25
+
+// HighlightFill is the fill of cells flagged by the highlight validation mode.
+const HighlightFill = "FF9999"
+
+// Validation modes of Config.Validate.
+const (
+	ValidateAbort     = "abort"     // fail on roster issues (default)
+	ValidateHighlight = "highlight" // mark the offending cells
+	ValidateOff       = "off"       // write the roster unchecked
+)
+
+// Job is a Config resolved into the values the template handlers take.
+// Employees is filled by LoadEmployees, or set by the caller.
+type Job struct {
+	Config    *Config
+	Period    domain.Period
+	Calendar  *calendar.Calendar
+	Codes     *domain.Codes
+	Eval      template.Evaluation
+	Employees []domain.Employee
+
+	highlight *domain.ValidateOptions
+}
+
+// New resolves c: it parses the period and evaluation mode, loads the holiday
+// file and checks the stages and formula keys. Employees are not loaded.
+func New(c *Config) (*Job, error) {
+	j := &Job{
+		Config:   c,
+		Period:   domain.CurrentPeriod(),
+		Calendar: calendar.New(),
+		Codes:    domain.DefaultCodes(),
+	}
+
+	if c.Period != "" {
+		p, err := domain.ParsePeriod(c.Period)
+		if err != nil {
+			return nil, fmt.Errorf("period: %w", err)
+		}
+		j.Period = p
+	}
+
+	if c.Holidays != "" {
+		if err := j.Calendar.LoadFile(c.Holidays); err != nil {
+			return nil, fmt.Errorf("holidays: %w", err)
+		}
+	}
+
+	eval, err := ParseEvaluation(c.Eval)
+	if err != nil {
+		return nil, fmt.Errorf("eval: %w", err)
+	}
+	j.Eval = eval
+
+	switch c.Validate {
+	case "", ValidateAbort, ValidateHighlight, ValidateOff:
+	default:
+		return nil, fmt.Errorf("validate: unknown mode %q (want abort, highlight or off)", c.Validate)
+	}
+
+	for _, name := range c.stages() {
+		switch name {
+		case StageStructure, StageFormulas, StageMerge, StageBorder:
+		default:
+			return nil, fmt.Errorf("stages: unknown stage %q", name)
+		}
+	}
+
+	if c.Employees.Generate < 0 {
+		return nil, fmt.Errorf("employees.generate: %d is negative", c.Employees.Generate)
+	}
+
+	if _, err := j.formulaKeys(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Prepare is New followed by LoadEmployees and Validate.
+// The validation report is returned for the highlight mode.
+func Prepare(c *Config) (*Job, *domain.Report, error) {
+	j, err := New(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := j.LoadEmployees(); err != nil {
+		return nil, nil, fmt.Errorf("employees: %w", err)
+	}
+	report, err := j.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("employees: %w", err)
+	}
+	return j, report, nil
+}
+
+// Clone returns a copy of j for another run of the same configuration, such
+// as one request of a server. Employees and the validation state are not
+// copied; the calendar and codes are shared and must not be changed. A job
+// without a configured period covers the month current at the time of the
+// call.
+func (j *Job) Clone() *Job {
+	c := &Job{
+		Config:   j.Config,
+		Period:   j.Period,
+		Calendar: j.Calendar,
+		Codes:    j.Codes,
+		Eval:     j.Eval,
+	}
+	if j.Config.Period == "" {
+		c.Period = domain.CurrentPeriod()
+	}
+	return c
+}
+
+// ParseEvaluation maps an evaluation mode name to a template.Evaluation;
+// "" is FormulasOnly.
+func ParseEvaluation(s string) (template.Evaluation, error) {
+	switch s {
+	case "", "formulas":
+		return template.FormulasOnly, nil
+	case "cached":
+		return template.CachedValues, nil
+	case "values":
+		return template.ValuesOnly, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q (want formulas, cached or values)", s)
+}
+
+// LoadEmployees reads the roster of the job, or generates random employees
+// (with rest days and holidays filled from the calendar) when it names none.
+func (j *Job) LoadEmployees() error {
+	if path := j.Config.Employees.Path; path != "" {
+		// In highlight mode the attendance length is checked with the rest
+		// of the roster validation, so it is marked instead of aborting the
+		// load.
+		opts := domain.LoadOptions{Period: j.Period}
+		if j.Config.Validate == ValidateHighlight {
+			opts.Period = domain.Period{}
+		}
+		employees, err := domain.LoadEmployees(path, opts)
+		if err != nil {
+			return err
+		}
+		j.Employees = employees
+		return nil
+	}
+
+	n := j.Config.Employees.Generate
+	if n == 0 {
+		n = DefaultEmployeeCount
+	}
+	j.Employees = domain.GenerateEmployeesForPeriod(n, j.Period)
+	for _, emp := range j.Employees {
+		j.Calendar.Fill(j.Period, emp.Attendance)
+	}
+	return nil
+}
+
+// Validate checks Employees according to the validation mode. In abort mode
+// roster issues are an error; in highlight mode they are returned and the
+// offending cells are marked by the pipeline.
+func (j *Job) Validate() (*domain.Report, error) {
+	j.highlight = nil
+	if j.Config.Validate == ValidateOff {
+		return &domain.Report{}, nil
+	}
+
+	opts := domain.ValidateOptions{Codes: j.Codes, Period: j.Period}
+	report := domain.ValidateEmployees(j.Employees, opts)
+	if report.OK() {
+		return report, nil
+	}
+	if j.Config.Validate == ValidateHighlight {
+		j.highlight = &opts
+		return report, nil
+	}
+	return report, report.Err()
+}
+
+// Pipeline builds the registries of the configured stages, in order.
+func (j *Job) Pipeline() (*processor.Pipeline, error) {
+	var stages []*template.Registry
+	for _, name := range j.Config.stages() {
+		switch name {
+		case StageStructure:
+			stages = append(stages, j.structure())
+		case StageFormulas:
+			keys, err := j.formulaKeys()
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, j.formulas(keys))
+		case StageMerge:
+			registry := template.New()
+			template.RegisterMergeHandler(registry)
+			stages = append(stages, registry)
+		case StageBorder:
+			registry := template.New()
+			template.RegisterBorderHandler(registry)
+			stages = append(stages, registry)
+		default:
+			return nil, fmt.Errorf("stages: unknown stage %q", name)
+		}
+	}
+
+	p := processor.NewPipeline(stages...)
+	p.SetKeepGoing(j.Config.KeepGoing)
+	return p, nil
+}
+
+// FormulaKeys returns the formula keys of the formulas stage.
+func (j *Job) FormulaKeys() []template.FormulaKey {
+	keys, _ := j.formulaKeys() // checked by New
+	return keys
+}
+
+// structure injects days, working_time, employee attendance rows, the marks
+// legend and the replace pairs.
+func (j *Job) structure() *template.Registry {
+	c := j.Config
+	registry := template.New()
+	registry.SetPeriod(j.Period)
+	registry.SetCalendar(j.Calendar)
+
+	locale := c.Days.Locale
+	if locale == "" {
+		locale = "tk"
+	}
+	registry.SetDaysOptions(template.DaysOptions{Weekdays: c.Days.Weekdays, Locale: locale, WeekendFill: c.Days.WeekendFill})
+	template.RegisterDefaults(registry)
+
+	employeeHandler := template.NewEmployeeHandler().Block(template.DefaultBlock, j.Employees)
+	if j.highlight != nil {
+		employeeHandler.Highlight(*j.highlight, HighlightFill)
+	}
+	employeeHandler.Register(registry)
+
+	marks := j.Codes.Marks()
+	if c.Marks != nil {
+		marks = make([]domain.Mark, len(c.Marks))
+		for i, m := range c.Marks {
+			marks[i] = domain.Mark{Name: m.Name, Key: m.Key}
+		}
+	}
+	template.RegisterMarksHandler(registry, marks)
+
+	if len(c.Replace) > 0 {
+		rh := template.NewReplaceHandler()
+		for _, p := range c.Replace {
+			rh.Add(p.Key, p.Value)
+		}
+		rh.Register(registry)
+	}
+
+	return registry
+}
+
+// formulas writes per-employee formulas for the formula keys below each
+// employee block.
+func (j *Job) formulas(keys []template.FormulaKey) *template.Registry {
+	registry := template.New()
+	registry.SetPeriod(j.Period)
+	registry.SetEvaluation(j.Eval)
+	template.RegisterFormulaHandler(registry, len(j.Employees), template.DetectAttendance, keys)
+	return registry
+}
+
+// formulaKeys maps the configured keys to template.FormulaKey, or returns
+// DefaultFormulaKeys when none are configured.
+func (j *Job) formulaKeys() ([]template.FormulaKey, error) {
+	if len(j.Config.Keys) == 0 {
+		return DefaultFormulaKeys(j.Codes), nil
+	}
+
+	var keys []template.FormulaKey
+	for i, k := range j.Config.Keys {
+		if k.Type == KeyCodes {
+			keys = append(keys, template.CodeFormulaKeys(j.Codes)...)
+			continue
+		}
+		if k.Key == "" {
+			return nil, fmt.Errorf("formula key %d: missing key", i+1)
+		}
+
+		if k.Weight < 0 {
+			return nil, fmt.Errorf("formula key %s: weight: %d is negative", k.Key, k.Weight)
+		}
+
+		key := template.FormulaKey{Key: k.Key}
+		switch k.Type {
+		case KeyCount:
+			if k.Symbol == "" {
+				return nil, fmt.Errorf("formula key %s: count needs a symbol", k.Key)
+			}
+			weight := k.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			key.Formula, key.ValueFn = template.CountIFExpr(k.Symbol, weight), template.CountIFValue(k.Symbol, weight)
+		case KeySumNum:
+			key.Formula, key.ValueFn = template.SumNumExpr(), template.SumNumValue()
+		case KeyCountNum:
+			key.Formula, key.ValueFn = template.CountNumExpr(), template.CountNumValue()
+		case KeyHours:
+			key.Formula, key.ValueFn = template.HoursExpr(k.Symbol), template.HoursValue(k.Symbol)
+		case KeyNightHours:
+			key.Formula, key.ValueFn = template.NightHoursExpr(k.Symbol), template.NightHoursValue(k.Symbol)
+		case KeyOvertime:
+			key.Formula, key.ValueFn = template.OvertimeExpr(k.Symbol), template.OvertimeValue(k.Symbol)
+		case KeyHourEntries:
+			key.Formula, key.ValueFn = template.HourEntriesExpr(k.Symbol), template.HourEntriesValue(k.Symbol)
+		case KeyStyle:
+		default:
+			return nil, fmt.Errorf("formula key %s: unknown type %q", k.Key, k.Type)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// DefaultFormulaKeys returns the formula keys of a job without formula_keys:
+// the keys of codes plus {{num_sum}}, {{num_count}}, {{hours}},
+// {{hour_entries}}, {{night_hours}}, {{overtime}} and the style-only {{}}.
+//
+// {{num_sum}} and {{num_count}} keep their numeric-cell semantics; {{hours}}
+// and {{hour_entries}} read hour entries such as "8/2" instead.
+func DefaultFormulaKeys(codes *domain.Codes) []template.FormulaKey {
+	return append(template.CodeFormulaKeys(codes),
+		template.FormulaKey{Key: "{{num_sum}}", Formula: template.SumNumExpr(), ValueFn: template.SumNumValue()},
+		template.FormulaKey{Key: "{{num_count}}", Formula: template.CountNumExpr(), ValueFn: template.CountNumValue()},
+		template.FormulaKey{Key: "{{hours}}", Formula: template.HoursExpr(""), ValueFn: template.HoursValue("")},
+		template.FormulaKey{Key: "{{hour_entries}}", Formula: template.HourEntriesExpr(""), ValueFn: template.HourEntriesValue("")},
+		template.FormulaKey{Key: "{{night_hours}}", Formula: template.NightHoursExpr(""), ValueFn: template.NightHoursValue("")},
+		template.FormulaKey{Key: "{{overtime}}", Formula: template.OvertimeExpr(""), ValueFn: template.OvertimeValue("")},
+		template.FormulaKey{Key: "{{}}"},
+	)
+}
+
+// stages returns the configured stages, or DefaultStages.
+func (c *Config) stages() []string {
+	if len(c.Stages) == 0 {
+		return DefaultStages
+	}
+	return c.Stages
+}
@@ -0,0 +1,206 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/orayew2002/rast-excel/calendar"
+	"github.com/orayew2002/rast-excel/domain"
+)
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"negative generate", "employees: {generate: -3}", "employees.generate: -3 is negative"},
+		{"negative weight", `formula_keys: [{key: "{{x}}", type: count, symbol: W, weight: -8}]`, "formula key {{x}}: weight: -8 is negative"},
+		{"count without symbol", `formula_keys: [{key: "{{x}}", type: count}]`, "formula key {{x}}: count needs a symbol"},
+		{"unknown key type", `formula_keys: [{key: "{{x}}", type: sum}]`, `formula key {{x}}: unknown type "sum"`},
+		{"missing key", `formula_keys: [{type: hours}]`, "formula key 1: missing key"},
+		{"unknown stage", "stages: [structure, colour]", `stages: unknown stage "colour"`},
+		{"unknown validate mode", "validate: warn", `validate: unknown mode "warn" (want abort, highlight or off)`},
+		{"unknown eval mode", "eval: lazy", `eval: unknown mode "lazy" (want formulas, cached or values)`},
+		{"bad period", "period: 2026-13", "period: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.yaml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = New(c)
+			if err == nil {
+				t.Fatalf("New: want an error %q", tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("New error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	c, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.LoadEmployees(); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.Employees) != DefaultEmployeeCount {
+		t.Errorf("generated %d employees, want %d", len(j.Employees), DefaultEmployeeCount)
+	}
+	if _, err := j.Pipeline(); err != nil {
+		t.Errorf("Pipeline: %v", err)
+	}
+}
+
+func TestFilledRestDaysAreNotWorked(t *testing.T) {
+	c, err := Parse([]byte("period: 2026-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// February 2026 has 8 weekend days and 20 working days of 8 hours.
+	attendance := make([]string, j.Period.Days())
+	for i := range attendance {
+		attendance[i] = "8"
+	}
+	j.Calendar.Fill(j.Period, attendance)
+
+	values := make(map[string]float64)
+	for _, k := range DefaultFormulaKeys(j.Codes) {
+		if k.ValueFn != nil {
+			values[k.Key] = k.ValueFn(attendance)
+		}
+	}
+	want := map[string]float64{
+		"{{worked_days}}":  20,
+		"{{worked_hours}}": 160,
+		"{{w}}":            0,
+		"{{w_hours}}":      0,
+		"{{b}}":            8,
+	}
+	for key, v := range want {
+		if values[key] != v {
+			t.Errorf("%s = %v, want %v", key, values[key], v)
+		}
+	}
+
+	if err := j.LoadEmployees(); err != nil {
+		t.Fatal(err)
+	}
+	for _, emp := range j.Employees {
+		for i, kind := range j.Calendar.Kinds(j.Period) {
+			if kind != calendar.Workday && emp.Attendance[i] != "B" {
+				t.Fatalf("employee %d, day %d: %q on a day off, want B", emp.Id, i+1, emp.Attendance[i])
+			}
+		}
+	}
+}
+
+func TestHourFormulaKeys(t *testing.T) {
+	c, err := Parse([]byte(`formula_keys:
+  - {key: "{{entries}}", type: hour_entries}
+  - {key: "{{y_entries}}", type: hour_entries, symbol: "Y"}
+  - {key: "{{hours}}", type: hours}
+  - {key: "{{night}}", type: night_hours}
+  - {key: "{{over}}", type: overtime}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attendance := []string{"8", "8/2", "Y 4", "W", "", "4+1"}
+	want := map[string]float64{
+		"{{entries}}":   3,
+		"{{y_entries}}": 1,
+		"{{hours}}":     20,
+		"{{night}}":     2,
+		"{{over}}":      1,
+	}
+	keys := j.FormulaKeys()
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(keys), len(want))
+	}
+	for _, k := range keys {
+		if k.Formula == nil || k.ValueFn == nil {
+			t.Errorf("%s: missing Formula or ValueFn", k.Key)
+			continue
+		}
+		if got := k.ValueFn(attendance); got != want[k.Key] {
+			t.Errorf("%s = %v, want %v", k.Key, got, want[k.Key])
+		}
+	}
+}
+
+func TestDefaultFormulaKeys(t *testing.T) {
+	attendance := []string{"8", "W", "4.5", "8/2", ""}
+	want := map[string]float64{
+		"{{num_sum}}":      12.5,
+		"{{num_count}}":    2,
+		"{{hours}}":        20.5,
+		"{{hour_entries}}": 3,
+	}
+
+	for _, k := range DefaultFormulaKeys(domain.DefaultCodes()) {
+		v, ok := want[k.Key]
+		if !ok {
+			continue
+		}
+		delete(want, k.Key)
+		if got := k.ValueFn(attendance); got != v {
+			t.Errorf("%s = %v, want %v", k.Key, got, v)
+		}
+	}
+	for key := range want {
+		t.Errorf("%s is not a default key", key)
+	}
+}
+
+func TestLoadEmployeesChecksLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roster.csv")
+	if err := os.WriteFile(path, []byte("name,attendance\nAman,\"8; 7,5\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		validate string
+		want     string // LoadEmployees error, "" for none
+	}{
+		{ValidateAbort, path + ": line 2: Attendance: has 2 entries, period 2026-02 has 28 days"},
+		{ValidateOff, path + ": line 2: Attendance: has 2 entries, period 2026-02 has 28 days"},
+		{ValidateHighlight, ""},
+	}
+
+	for _, tt := range tests {
+		j, err := New(&Config{Period: "2026-02", Validate: tt.validate, Employees: Employees{Path: path}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if err := j.LoadEmployees(); err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("validate %s: LoadEmployees error = %q, want %q", tt.validate, got, tt.want)
+		}
+	}
+}
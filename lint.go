@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/orayew2002/rast-excel/job"
+	"github.com/orayew2002/rast-excel/processor"
+	"github.com/orayew2002/rast-excel/template"
+)
+
+// runLint implements `rast-excel lint [-format text|json] template.xlsx`:
+// it reports template problems and exits 1 when there are any.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	configPath := fs.String("config", "", "path to a YAML job file whose formula keys are checked")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rast-excel lint [-config job.yaml] [-format text|json] template.xlsx")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		return 1
+	}
+	j, err := job.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	report, err := processor.LintFile(fs.Arg(0), template.LintOptions{Keys: j.FormulaKeys()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		printLintReport(os.Stdout, report)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "format: unknown format %q (want text or json)\n", *format)
+		return 2
+	}
+
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// printLintReport writes report as a table, or a one-line all-clear.
+func printLintReport(w io.Writer, report *template.LintReport) {
+	if report.OK() {
+		fmt.Fprintln(w, "no problems found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SHEET\tCELL\tKIND\tPROBLEM")
+	for _, i := range report.Issues {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", i.Sheet, i.Cell, i.Kind, i.Message)
+	}
+	tw.Flush()
+}
@@ -1,115 +1,52 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/orayew2002/rast-excel/domain"
-	"github.com/orayew2002/rast-excel/processor"
-	"github.com/orayew2002/rast-excel/template"
+	"github.com/orayew2002/rast-excel/job"
 )
 
-const employeeCount = 25
+const usage = `usage: rast-excel <command> [flags]
 
-func main() {
-	input := flag.String("input", "table.xlsx", "path to the input Excel file")
-	output := flag.String("output", "result.xlsx", "path to the output Excel file")
-	flag.Parse()
-
-	// Step 1: inject days, working_time, and employee attendance rows.
-	data, err := step1(*input)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "step1: %v\n", err)
-		os.Exit(1)
-	}
+commands:
+  render   fill a template (default when the first argument is a flag)
+  inspect  list the placeholders a template uses
+  lint     check a template for broken markers and merge codes
+  serve    run the HTTP rendering service
 
-	// Step 2: write per-employee formulas for any {{key}} cells below the employee block.
-	data, err = step2(data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "step2: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Step 3: apply [rowSpan:colSpan] merge codes embedded in cell values.
-	data, err = step3(data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "step3: %v\n", err)
-		os.Exit(1)
-	}
+Run "rast-excel <command> -h" for the flags of a command.
+`
 
-	// Step 4: apply borders to &1…&1 ranges.
-	data, err = step4(data)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "step4: %v\n", err)
-		os.Exit(1)
+func main() {
+	cmd, args := "render", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
 	}
 
-	if err := os.WriteFile(*output, data, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "save: %v\n", err)
-		os.Exit(1)
+	switch cmd {
+	case "render":
+		os.Exit(runRender(args))
+	case "inspect":
+		os.Exit(runInspect(args))
+	case "lint":
+		os.Exit(runLint(args))
+	case "serve":
+		os.Exit(runServe(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
 	}
-
-	fmt.Println("done:", *output)
-}
-
-var marks = []domain.Mark{
-	{Name: "Dynç alyş we baýramçylyk günler", Key: "B"},
-	{Name: "Kanuna laýyk işe gelmezlik", Key: "C"},
-	{Name: "Gulluk iş saparlary", Key: "W"},
-	{Name: "Nobatdaky we goşmaça rugsatlar", Key: "O"},
-	{Name: "Işe ýarawsyzlyk (kesel, karantin we ş.m.)", Key: "Y"},
-	{Name: "Gowrelilik sebäpli rugsat", Key: "O"},
-	{Name: "Emdiryän eneleriň ýeňillikli sagatlary", Key: "I"},
-	{Name: "Saglyga zyýanly önümçilikde işleýän işleriň ýeňillikli sagatlary", Key: "ÝS"},
-	{Name: "Iş wagtyndan daşary edilen işiň sagatlary", Key: "IWI"},
-	{Name: "Bütin smena boýunça işsiz durmaklyk", Key: "ID"},
-	{Name: "Smeniň içindäki işsiz durmaklyk", Key: "IID"},
-	{Name: "Sebäpsiz işden galmak", Key: "S"},
-	{Name: "Işe gijä galmak we işden wagtyndan öň gitmek", Key: "SIG"},
-	{Name: "Kärhanañ çäginden daşary gulluk tabşyryklaryny ýerine ýetirmek", Key: "ÇDG"},
-	{Name: "Administrasiýañ rugsady boýunça işe gelmezlik", Key: "AR"},
-}
-
-func step1(input string) ([]byte, error) {
-	registry := template.New()
-	template.RegisterDefaults(registry)
-
-	employees := domain.GenerateEmployees(employeeCount)
-	template.RegisterEmployeeHandler(registry, employees)
-
-	template.RegisterMarksHandler(registry, marks)
-
-	return processor.New(registry).ProcessFile(input)
-}
-
-func step3(data []byte) ([]byte, error) {
-	registry := template.New()
-	template.RegisterMergeHandler(registry)
-	return processor.New(registry).ProcessBytes(data)
 }
 
-func step4(data []byte) ([]byte, error) {
-	registry := template.New()
-	template.RegisterBorderHandler(registry)
-	return processor.New(registry).ProcessBytes(data)
-}
-
-func step2(data []byte) ([]byte, error) {
-	registry := template.New()
-
-	attStart := template.AttendanceStartCol(0)
-	template.RegisterFormulaHandler(registry, employeeCount, attStart, []template.FormulaKey{
-		{Key: "{{t}}", FormulaFn: template.CountIFFormula("T", 1)},
-		{Key: "{{d}}", FormulaFn: template.CountIFFormula("D", 1)},
-		{Key: "{{w}}", FormulaFn: template.CountIFFormula("W", 1)},
-		{Key: "{{l}}", FormulaFn: template.CountIFFormula("L", 1)},
-		{Key: "{{a}}", FormulaFn: template.CountIFFormula("A", 1)},
-		{Key: "{{p}}", FormulaFn: template.CountIFFormula("P", 1)},
-		{Key: "{{num_sum}}", FormulaFn: template.SumNumFormula()},
-		{Key: "{{num_count}}", FormulaFn: template.CountNumFormula()},
-		{Key: "{{}}", FormulaFn: nil},
-	})
-
-	return processor.New(registry).ProcessBytes(data)
+// loadConfig reads the job file at path, or returns the default job when
+// path is empty.
+func loadConfig(path string) (*job.Config, error) {
+	if path == "" {
+		return &job.Config{}, nil
+	}
+	return job.Load(path)
 }
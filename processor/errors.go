@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/template"
+)
+
+// CellError is one failure collected in keep-going mode.
+//
+// Stage is the 1-based pipeline stage (0 for a plain Processor). Cell is the
+// A1-style name of the failing cell and Placeholder the text its handler
+// matched; both are empty for failures that concern the whole sheet, such as
+// an unreadable sheet.
+type CellError struct {
+	Stage       int
+	Sheet       string
+	Cell        string
+	Placeholder string
+	Err         error
+}
+
+func (e *CellError) Error() string {
+	var b strings.Builder
+	if e.Stage > 0 {
+		fmt.Fprintf(&b, "stage %d: ", e.Stage)
+	}
+	fmt.Fprintf(&b, "sheet %q: ", e.Sheet)
+	if e.Cell != "" {
+		fmt.Fprintf(&b, "cell %s: ", e.Cell)
+	}
+	if e.Placeholder != "" {
+		fmt.Fprintf(&b, "%s: ", e.Placeholder)
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// CanceledError is returned when the context of a run is done before the run
+// finishes. Sheet and Cell name the last processed cell; both are empty when
+// the run was cancelled before its first cell. Err is the context's error, so
+// errors.Is(err, context.DeadlineExceeded) works on the result.
+type CanceledError struct {
+	Sheet string
+	Cell  string
+	Err   error
+}
+
+func (e *CanceledError) Error() string {
+	if e.Cell == "" {
+		return fmt.Sprintf("cancelled before the first cell: %v", e.Err)
+	}
+	return fmt.Sprintf("cancelled after sheet %q cell %s: %v", e.Sheet, e.Cell, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every CellError of a keep-going run, in processing order.
+type Errors struct {
+	Cells []*CellError
+}
+
+func (e *Errors) Error() string {
+	lines := make([]string, len(e.Cells))
+	for i, c := range e.Cells {
+		lines[i] = c.Error()
+	}
+	return fmt.Sprintf("%d cell error(s):\n%s", len(e.Cells), strings.Join(lines, "\n"))
+}
+
+// Unwrap exposes the collected errors to errors.Is and errors.As.
+func (e *Errors) Unwrap() []error {
+	errs := make([]error, len(e.Cells))
+	for i, c := range e.Cells {
+		errs[i] = c
+	}
+	return errs
+}
+
+// add records a handler error for cell, taking the placeholder from a
+// template.HandlerError.
+func (e *Errors) add(sheet, cell string, err error) {
+	c := &CellError{Sheet: sheet, Cell: cell, Err: err}
+	var herr *template.HandlerError
+	if errors.As(err, &herr) {
+		c.Placeholder = herr.Placeholder()
+		c.Err = herr.Err
+	}
+	e.Cells = append(e.Cells, c)
+}
+
+// err returns e, or nil when nothing was collected.
+func (e *Errors) err() error {
+	if len(e.Cells) == 0 {
+		return nil
+	}
+	return e
+}
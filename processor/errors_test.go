@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/orayew2002/rast-excel/template"
+	"github.com/xuri/excelize/v2"
+)
+
+var errBoom = errors.New("boom")
+
+// failingRegistry fails every {{bad…}} cell and writes "done" into every
+// {{ok}} cell.
+func failingRegistry() *template.Registry {
+	r := template.New()
+	r.RegisterRegexp(regexp.MustCompile(`\{\{bad[^}]*\}\}`), func(ctx *template.Context) error { return errBoom })
+	r.Register("{{ok}}", func(ctx *template.Context) error {
+		return ctx.File.SetCellStr(ctx.Sheet, ctx.Cell(), "done")
+	})
+	return r
+}
+
+func TestCellError(t *testing.T) {
+	tests := []struct {
+		err  CellError
+		want string
+	}{
+		{CellError{Sheet: "S", Cell: "A1", Placeholder: "{{t}}", Err: errBoom}, `sheet "S": cell A1: {{t}}: boom`},
+		{CellError{Stage: 2, Sheet: "S", Cell: "A1", Placeholder: "{{t}}", Err: errBoom}, `stage 2: sheet "S": cell A1: {{t}}: boom`},
+		{CellError{Sheet: "S", Cell: "B3", Err: errBoom}, `sheet "S": cell B3: boom`},
+		{CellError{Stage: 1, Sheet: "S", Err: errBoom}, `stage 1: sheet "S": boom`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+		if !errors.Is(&tt.err, errBoom) {
+			t.Errorf("%q does not unwrap to its Err", tt.want)
+		}
+	}
+}
+
+func TestKeepGoing(t *testing.T) {
+	data := templateXLSX(t, [][]string{
+		{"{{bad}}", "{{ok}}"},
+		{"x", "{{bad two}}"},
+		{"{{ok}}"},
+	})
+
+	tests := []struct {
+		name      string
+		process   func(keepGoing bool) ([]byte, error)
+		stopError string       // error without keep-going
+		want      []*CellError // errors collected with keep-going
+	}{
+		{
+			name: "processor",
+			process: func(keepGoing bool) ([]byte, error) {
+				p := New(failingRegistry())
+				p.SetKeepGoing(keepGoing)
+				return p.ProcessBytes(data)
+			},
+			stopError: `sheet "Sheet1": cell A1: {{bad}}: boom`,
+			want: []*CellError{
+				{Sheet: "Sheet1", Cell: "A1", Placeholder: "{{bad}}", Err: errBoom},
+				{Sheet: "Sheet1", Cell: "B2", Placeholder: "{{bad two}}", Err: errBoom},
+			},
+		},
+		{
+			// Both stages fail on the same cells, so each cell has one error
+			// per stage.
+			name: "pipeline",
+			process: func(keepGoing bool) ([]byte, error) {
+				p := NewPipeline(failingRegistry(), failingRegistry())
+				p.SetKeepGoing(keepGoing)
+				return p.ProcessBytes(data)
+			},
+			stopError: `stage 1: sheet "Sheet1": cell A1: {{bad}}: boom`,
+			want: []*CellError{
+				{Stage: 1, Sheet: "Sheet1", Cell: "A1", Placeholder: "{{bad}}", Err: errBoom},
+				{Stage: 1, Sheet: "Sheet1", Cell: "B2", Placeholder: "{{bad two}}", Err: errBoom},
+				{Stage: 2, Sheet: "Sheet1", Cell: "A1", Placeholder: "{{bad}}", Err: errBoom},
+				{Stage: 2, Sheet: "Sheet1", Cell: "B2", Placeholder: "{{bad two}}", Err: errBoom},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.process(false)
+			if out != nil {
+				t.Error("a failed run returned a result")
+			}
+			if err == nil || err.Error() != tt.stopError {
+				t.Errorf("error = %v, want %q", err, tt.stopError)
+			}
+
+			out, err = tt.process(true)
+			var errs *Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("error = %v, want *Errors", err)
+			}
+			if !reflect.DeepEqual(errs.Cells, tt.want) {
+				t.Errorf("collected errors:\n%v\nwant\n%v", errs, &Errors{Cells: tt.want})
+			}
+			if !errors.Is(err, errBoom) {
+				t.Error("*Errors does not unwrap to the handler errors")
+			}
+
+			f, ferr := excelize.OpenReader(bytes.NewReader(out))
+			if ferr != nil {
+				t.Fatalf("keep-going result: %v", ferr)
+			}
+			defer f.Close()
+			for _, cell := range []string{"B1", "A3"} {
+				if v, _ := f.GetCellValue("Sheet1", cell); v != "done" {
+					t.Errorf("%s = %q, want done: cells after a failure are still processed", cell, v)
+				}
+			}
+		})
+	}
+}
+
+func TestKeepGoingWithoutFailures(t *testing.T) {
+	p := New(failingRegistry())
+	p.SetKeepGoing(true)
+	if _, err := p.ProcessBytes(templateXLSX(t, [][]string{{"{{ok}}"}})); err != nil {
+		t.Errorf("error = %v, want nil", err)
+	}
+}
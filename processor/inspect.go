@@ -0,0 +1,174 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/orayew2002/rast-excel/template"
+	"github.com/xuri/excelize/v2"
+)
+
+// placeholderPat matches {{…}}-looking text, whether or not it parses as a
+// template.Placeholder.
+var placeholderPat = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// Finding is one templated cell reported by an inspection.
+//
+// Stage is the 1-based pipeline stage whose registry matched the cell (0 for a
+// plain Processor and for unhandled text). Pattern, Handler and Match describe
+// the handler the cell would be dispatched to; for unhandled text Match holds
+// the placeholder no handler claims.
+type Finding struct {
+	Stage   int    `json:"stage,omitempty"`
+	Sheet   string `json:"sheet"`
+	Cell    string `json:"cell"`
+	Value   string `json:"value"`
+	Pattern string `json:"pattern,omitempty"`
+	Handler string `json:"handler,omitempty"`
+	Match   string `json:"match"`
+}
+
+// Inspection lists what a template expects: every cell a handler matches and
+// every placeholder no handler matches or claims, in sheet and row-major order.
+type Inspection struct {
+	Matches   []Finding `json:"matches"`
+	Unhandled []Finding `json:"unhandled"`
+}
+
+// InspectFile scans an Excel file from disk with the registry and reports
+// the placeholders it finds. No handler runs and nothing is written.
+func (p *Processor) InspectFile(input string) (*Inspection, error) {
+	return p.InspectFileContext(context.Background(), input)
+}
+
+// InspectFileContext is InspectFile with a context, checked between rows; a
+// cancelled inspection returns a *CanceledError.
+func (p *Processor) InspectFileContext(ctx context.Context, input string) (*Inspection, error) {
+	f, err := excelize.OpenFile(input)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", input, err)
+	}
+	defer f.Close()
+
+	return inspect(ctx, f, []*template.Registry{p.registry}, false)
+}
+
+// InspectBytes is InspectFile for an Excel file held in memory.
+func (p *Processor) InspectBytes(data []byte) (*Inspection, error) {
+	return p.InspectBytesContext(context.Background(), data)
+}
+
+// InspectBytesContext is InspectBytes with a context (see InspectFileContext).
+func (p *Processor) InspectBytesContext(ctx context.Context, data []byte) (*Inspection, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	defer f.Close()
+
+	return inspect(ctx, f, []*template.Registry{p.registry}, false)
+}
+
+// InspectFile scans an Excel file from disk with the registry of every stage.
+// All stages see the template as it is on disk — rows that earlier stages
+// would insert are not there yet. A placeholder handled by any stage is not
+// reported as unhandled.
+func (p *Pipeline) InspectFile(input string) (*Inspection, error) {
+	return p.InspectFileContext(context.Background(), input)
+}
+
+// InspectFileContext is InspectFile with a context (see
+// Processor.InspectFileContext).
+func (p *Pipeline) InspectFileContext(ctx context.Context, input string) (*Inspection, error) {
+	f, err := excelize.OpenFile(input)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", input, err)
+	}
+	defer f.Close()
+
+	return inspect(ctx, f, p.registries(), true)
+}
+
+// InspectBytes is InspectFile for an Excel file held in memory.
+func (p *Pipeline) InspectBytes(data []byte) (*Inspection, error) {
+	return p.InspectBytesContext(context.Background(), data)
+}
+
+// InspectBytesContext is InspectBytes with a context (see
+// Processor.InspectFileContext).
+func (p *Pipeline) InspectBytesContext(ctx context.Context, data []byte) (*Inspection, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	defer f.Close()
+
+	return inspect(ctx, f, p.registries(), true)
+}
+
+func (p *Pipeline) registries() []*template.Registry {
+	registries := make([]*template.Registry, len(p.stages))
+	for i, stage := range p.stages {
+		registries[i] = stage.registry
+	}
+	return registries
+}
+
+// inspect looks up every non-empty cell of f in registries. staged numbers
+// the findings by registry.
+func inspect(ctx context.Context, f *excelize.File, registries []*template.Registry, staged bool) (*Inspection, error) {
+	in := &Inspection{Matches: []Finding{}, Unhandled: []Finding{}}
+	var lastSheet, lastCell string // for cancellation errors
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: get rows: %w", sheet, err)
+		}
+
+		for row, cells := range rows {
+			if err := ctx.Err(); err != nil {
+				return nil, &CanceledError{Sheet: lastSheet, Cell: lastCell, Err: err}
+			}
+			for col, value := range cells {
+				if value == "" {
+					continue
+				}
+				cell := excel.CellName(row, col)
+				lastSheet, lastCell = sheet, cell
+
+				for i, registry := range registries {
+					b, ok := registry.Lookup(value)
+					if !ok {
+						continue
+					}
+					finding := Finding{Sheet: sheet, Cell: cell, Value: value, Pattern: b.Pattern, Handler: b.Handler, Match: b.Match.Text}
+					if staged {
+						finding.Stage = i + 1
+					}
+					in.Matches = append(in.Matches, finding)
+				}
+
+				for _, text := range placeholderPat.FindAllString(value, -1) {
+					if !handled(registries, text) {
+						in.Unhandled = append(in.Unhandled, Finding{Sheet: sheet, Cell: cell, Value: value, Match: text})
+					}
+				}
+			}
+		}
+	}
+
+	return in, nil
+}
+
+// handled reports whether any registry handles text.
+func handled(registries []*template.Registry, text string) bool {
+	for _, registry := range registries {
+		if registry.Handles(text) {
+			return true
+		}
+	}
+	return false
+}
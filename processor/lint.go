@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/orayew2002/rast-excel/template"
+	"github.com/xuri/excelize/v2"
+)
+
+// LintFile opens an Excel template from disk and checks it with
+// template.Lint. The file is only read.
+func LintFile(input string, opts template.LintOptions) (*template.LintReport, error) {
+	return LintFileContext(context.Background(), input, opts)
+}
+
+// LintFileContext is LintFile with a context (see template.LintContext).
+func LintFileContext(ctx context.Context, input string, opts template.LintOptions) (*template.LintReport, error) {
+	f, err := excelize.OpenFile(input)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", input, err)
+	}
+	defer f.Close()
+
+	return template.LintContext(ctx, f, opts)
+}
+
+// LintBytes is LintFile for a template held in memory.
+func LintBytes(data []byte, opts template.LintOptions) (*template.LintReport, error) {
+	return LintBytesContext(context.Background(), data, opts)
+}
+
+// LintBytesContext is LintBytes with a context (see template.LintContext).
+func LintBytesContext(ctx context.Context, data []byte, opts template.LintOptions) (*template.LintReport, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	defer f.Close()
+
+	return template.LintContext(ctx, f, opts)
+}
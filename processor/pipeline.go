@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/orayew2002/rast-excel/template"
+	"github.com/xuri/excelize/v2"
+)
+
+// Pipeline runs an ordered list of stages against one in-memory workbook.
+//
+// Each stage is a registry processed over every sheet, exactly like
+// Processor; cells are re-scanned before each stage, so a stage sees the
+// rows and columns inserted by the stages before it. The workbook is opened
+// once and serialized once, instead of once per stage.
+type Pipeline struct {
+	stages         []*Processor
+	keepGoing      bool
+	keepBlockNames bool
+}
+
+// NewPipeline creates a Pipeline that runs the given registries in order.
+func NewPipeline(stages ...*template.Registry) *Pipeline {
+	p := &Pipeline{}
+	for _, registry := range stages {
+		p.stages = append(p.stages, New(registry))
+	}
+	return p
+}
+
+// SetKeepGoing selects keep-going mode for every stage (see
+// Processor.SetKeepGoing). A stage with failures does not stop the later
+// stages; the collected errors carry their stage number.
+func (p *Pipeline) SetKeepGoing(keepGoing bool) {
+	p.keepGoing = keepGoing
+	for _, stage := range p.stages {
+		stage.SetKeepGoing(keepGoing)
+	}
+}
+
+// SetKeepBlockNames keeps the defined names recording employee blocks in the
+// result (see Processor.SetKeepBlockNames).
+func (p *Pipeline) SetKeepBlockNames(keep bool) {
+	p.keepBlockNames = keep
+}
+
+// ProcessFile opens an Excel file from disk, runs every stage,
+// and returns the result as bytes. It does NOT save to disk.
+func (p *Pipeline) ProcessFile(input string) ([]byte, error) {
+	return p.ProcessFileContext(context.Background(), input)
+}
+
+// ProcessFileContext is ProcessFile with a context. Cancellation is checked
+// between stages, sheets and cells (see Processor.ProcessFileContext).
+func (p *Pipeline) ProcessFileContext(ctx context.Context, input string) ([]byte, error) {
+	return processFile(ctx, input, p.workbook)
+}
+
+// ProcessBytes opens an Excel file from raw bytes, runs every stage,
+// and returns the result as bytes.
+func (p *Pipeline) ProcessBytes(data []byte) ([]byte, error) {
+	return p.ProcessBytesContext(context.Background(), data)
+}
+
+// ProcessBytesContext is ProcessBytes with a context (see ProcessFileContext).
+func (p *Pipeline) ProcessBytesContext(ctx context.Context, data []byte) ([]byte, error) {
+	return processBytes(ctx, data, p.workbook)
+}
+
+// Process reads an Excel file from r, runs every stage and writes the result
+// to w (see Processor.Process).
+func (p *Pipeline) Process(r io.Reader, w io.Writer) error {
+	return p.ProcessContext(context.Background(), r, w)
+}
+
+// ProcessContext is Process with a context (see ProcessFileContext).
+func (p *Pipeline) ProcessContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	return processStream(ctx, r, w, p.workbook)
+}
+
+// ProcessToFile opens the Excel file input, runs every stage and writes the
+// result to output (see Processor.ProcessToFile).
+func (p *Pipeline) ProcessToFile(input, output string) error {
+	return p.ProcessToFileContext(context.Background(), input, output)
+}
+
+// ProcessToFileContext is ProcessToFile with a context (see
+// ProcessFileContext).
+func (p *Pipeline) ProcessToFileContext(ctx context.Context, input, output string) error {
+	return processToFile(ctx, input, output, p.workbook)
+}
+
+// workbook runs every stage in one template.Run, so stages share the style
+// cache and the handler store.
+func (p *Pipeline) workbook(ctx context.Context, f *excelize.File) (*Errors, error) {
+	s := newScan(ctx, f)
+	for i, stage := range p.stages {
+		collected := len(s.errs.Cells)
+		if err := stage.processWorkbook(s); err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i+1, err)
+		}
+		for _, c := range s.errs.Cells[collected:] {
+			c.Stage = i + 1
+		}
+	}
+	if !p.keepBlockNames {
+		template.RemoveBlockNames(f)
+	}
+
+	return s.errs, nil
+}
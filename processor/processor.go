@@ -1,8 +1,10 @@
 package processor
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/orayew2002/rast-excel/excel"
 	"github.com/orayew2002/rast-excel/template"
@@ -11,7 +13,9 @@ import (
 
 // Processor applies registered template handlers to Excel files.
 type Processor struct {
-	registry *template.Registry
+	registry       *template.Registry
+	keepGoing      bool
+	keepBlockNames bool
 }
 
 // New creates a Processor with the given template registry.
@@ -19,64 +23,209 @@ func New(registry *template.Registry) *Processor {
 	return &Processor{registry: registry}
 }
 
+// SetKeepGoing selects keep-going mode: instead of stopping at the first
+// failing cell, every cell and sheet error is collected and the rest of the
+// workbook is still processed. The result is then returned together with an
+// *Errors listing the failures.
+func (p *Processor) SetKeepGoing(keepGoing bool) {
+	p.keepGoing = keepGoing
+}
+
+// SetKeepBlockNames keeps the defined names recording employee blocks
+// (rast_<block>, rastscope_<block>, rastgrp_<block>_<n>) in the result. By
+// default they are removed before the workbook is written; keep them when the
+// result is fed to another Processor whose formula handler uses
+// template.DetectAttendance.
+func (p *Processor) SetKeepBlockNames(keep bool) {
+	p.keepBlockNames = keep
+}
+
 // ProcessFile opens an Excel file from disk, processes all sheets,
 // and returns the result as bytes. It does NOT save to disk.
 func (p *Processor) ProcessFile(input string) ([]byte, error) {
-	f, err := excelize.OpenFile(input)
-	if err != nil {
-		return nil, fmt.Errorf("open %s: %w", input, err)
-	}
-	defer f.Close()
+	return p.ProcessFileContext(context.Background(), input)
+}
 
-	return p.process(f)
+// ProcessFileContext is ProcessFile with a context. Cancellation is checked
+// between cells and sheets, and handlers see ctx through
+// template.Context.Context; a cancelled run returns a *CanceledError.
+func (p *Processor) ProcessFileContext(ctx context.Context, input string) ([]byte, error) {
+	return processFile(ctx, input, p.workbook)
 }
 
 // ProcessBytes opens an Excel file from raw bytes, processes all sheets,
 // and returns the result as bytes.
 func (p *Processor) ProcessBytes(data []byte) ([]byte, error) {
-	f, err := excelize.OpenReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("open from bytes: %w", err)
+	return p.ProcessBytesContext(context.Background(), data)
+}
+
+// ProcessBytesContext is ProcessBytes with a context (see ProcessFileContext).
+func (p *Processor) ProcessBytesContext(ctx context.Context, data []byte) ([]byte, error) {
+	return processBytes(ctx, data, p.workbook)
+}
+
+// Process reads an Excel file from r, processes all sheets and writes the
+// result to w. The workbook is serialized straight into w, without an
+// intermediate copy; nothing is written when processing fails. (The input is
+// still read whole: an xlsx file is a zip archive, which needs random access.)
+func (p *Processor) Process(r io.Reader, w io.Writer) error {
+	return p.ProcessContext(context.Background(), r, w)
+}
+
+// ProcessContext is Process with a context (see ProcessFileContext).
+func (p *Processor) ProcessContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	return processStream(ctx, r, w, p.workbook)
+}
+
+// ProcessToFile opens the Excel file input, processes all sheets and writes
+// the result to output. The output file is only created once processing has
+// succeeded.
+func (p *Processor) ProcessToFile(input, output string) error {
+	return p.ProcessToFileContext(context.Background(), input, output)
+}
+
+// ProcessToFileContext is ProcessToFile with a context (see
+// ProcessFileContext).
+func (p *Processor) ProcessToFileContext(ctx context.Context, input, output string) error {
+	return processToFile(ctx, input, output, p.workbook)
+}
+
+// workbook processes f in place and returns the errors collected in
+// keep-going mode.
+func (p *Processor) workbook(ctx context.Context, f *excelize.File) (*Errors, error) {
+	s := newScan(ctx, f)
+	if err := p.processWorkbook(s); err != nil {
+		return nil, err
+	}
+	if !p.keepBlockNames {
+		template.RemoveBlockNames(f)
 	}
-	defer f.Close()
+	return s.errs, nil
+}
+
+// scan is the state of one processing run, shared by its sheets and
+// pipeline stages.
+type scan struct {
+	run  *template.Run
+	errs *Errors // failures collected in keep-going mode
 
-	return p.process(f)
+	// last processed cell, for cancellation errors
+	sheet string
+	cell  string
 }
 
-// process runs all sheet handlers and serializes the result to bytes.
-func (p *Processor) process(f *excelize.File) ([]byte, error) {
-	for _, sheet := range f.GetSheetList() {
-		if err := p.processSheet(f, sheet); err != nil {
-			return nil, fmt.Errorf("sheet %q: %w", sheet, err)
-		}
+func newScan(ctx context.Context, f *excelize.File) *scan {
+	return &scan{run: template.NewRunContext(ctx, f), errs: &Errors{}}
+}
+
+// canceled returns a *CanceledError once the run's context is done.
+func (s *scan) canceled() error {
+	if err := s.run.Context().Err(); err != nil {
+		return &CanceledError{Sheet: s.sheet, Cell: s.cell, Err: err}
 	}
+	return nil
+}
 
-	buf, err := f.WriteToBuffer()
-	if err != nil {
-		return nil, fmt.Errorf("write to buffer: %w", err)
+// processWorkbook runs the registry over every sheet of the run's file in
+// place. In keep-going mode failures are added to s.errs and processing
+// continues; cancellation always stops it.
+func (p *Processor) processWorkbook(s *scan) error {
+	for _, sheet := range s.run.File.GetSheetList() {
+		if err := s.canceled(); err != nil {
+			return err
+		}
+
+		err := p.processSheet(s, sheet)
+		if err == nil {
+			continue
+		}
+		var canceled *CanceledError
+		if errors.As(err, &canceled) {
+			return err
+		}
+		if !p.keepGoing {
+			return fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+		s.errs.add(sheet, "", err)
 	}
 
-	return buf.Bytes(), nil
+	return nil
 }
 
-func (p *Processor) processSheet(f *excelize.File, sheet string) error {
-	rows, err := f.GetRows(sheet)
+// processSheet scans the cells of sheet in row-major order. When a handler
+// inserts or removes rows or columns (through template.Context), the sheet is
+// re-read and the scan resumes after the handled cell at its new position:
+// rows and columns inserted at or before it are skipped, those after it are
+// scanned.
+//
+// A failing cell aborts the scan unless the processor keeps going; its error
+// is then added to s.errs and the scan moves on to the next cell.
+func (p *Processor) processSheet(s *scan, sheet string) error {
+	run := s.run
+	rows, err := run.File.GetRows(sheet)
 	if err != nil {
 		return fmt.Errorf("get rows: %w", err)
 	}
 
-	for row := range rows {
-		for col := range rows[row] {
+	for row := 0; row < len(rows); row++ {
+		for col := 0; row < len(rows) && col < len(rows[row]); col++ {
 			value := rows[row][col]
 			if value == "" {
 				continue
 			}
+			if err := s.canceled(); err != nil {
+				return err
+			}
+
+			cell := excel.CellName(row, col)
+			edits := len(run.Edits())
+			_, err := p.registry.Process(run, sheet, row, col, value)
+			s.sheet, s.cell = sheet, cell
+			if err != nil {
+				if cerr := s.canceled(); cerr != nil {
+					return cerr
+				}
+				if !p.keepGoing {
+					return fmt.Errorf("cell %s: %w", cell, err)
+				}
+				s.errs.add(sheet, cell, err)
+			}
+			if len(run.Edits()) == edits {
+				continue
+			}
 
-			if _, err := p.registry.Process(f, sheet, row, col, value); err != nil {
-				return fmt.Errorf("cell %s: %w", excel.CellName(row, col), err)
+			row, col = resume(run.Edits()[edits:], sheet, row, col)
+			if rows, err = run.File.GetRows(sheet); err != nil {
+				return fmt.Errorf("get rows: %w", err)
 			}
 		}
 	}
 
 	return nil
 }
+
+// resume maps the scan position (row, col) through edits. If the handled
+// cell's row was removed, the scan continues at the start of the row that took
+// its place; a removed column continues at the column that took its place.
+// The returned col is the last visited column, so the caller's col++ moves to
+// the next cell.
+func resume(edits []template.Edit, sheet string, row, col int) (int, int) {
+	for _, e := range edits {
+		if e.Sheet != sheet {
+			continue
+		}
+
+		var ok bool
+		switch e.Axis {
+		case template.Rows:
+			if row, ok = e.Shift(row); !ok {
+				col = -1
+			}
+		case template.Cols:
+			if col, ok = e.Shift(col); !ok {
+				col--
+			}
+		}
+	}
+	return row, col
+}
@@ -0,0 +1,285 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/template"
+	"github.com/xuri/excelize/v2"
+)
+
+// templateXLSX writes rows of cell values to Sheet1 of a new workbook.
+func templateXLSX(t *testing.T, rows [][]string) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, row := range rows {
+		for c, v := range row {
+			cell, _ := excelize.CoordinatesToCellName(c+1, i+1)
+			if err := f.SetCellStr("Sheet1", cell, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestFormulaRowsBindToTheirBlock runs employees and formulas as separate
+// stages, so every block is written before any formula row is reached.
+func TestFormulaRowsBindToTheirBlock(t *testing.T) {
+	period := domain.MonthPeriod(2026, time.February) // attendance in E:AF
+	const key = "{{w}}"
+
+	tests := []struct {
+		name string
+		rows [][]string // template rows; key sits in column AG
+		want []string   // per result row: the AG formula's attendance range, "" for none
+	}{
+		{
+			name: "two blocks",
+			rows: [][]string{
+				{"{{start_process sales}}"},
+				agCell(key),
+				{"{{start_process it}}"},
+				agCell(key),
+			},
+			want: []string{"E1:AF1", "E2:AF2", "E3:AF3"},
+		},
+		{
+			name: "rows block between a block and its formula row",
+			rows: [][]string{
+				{"{{start_process sales}}"},
+				{"{{#rows notes}}{{.Text}}{{/rows}}"},
+				agCell(key),
+				{"{{start_process it}}"},
+				agCell(key),
+			},
+			want: []string{"E1:AF1", "E2:AF2", "", "", "E5:AF5"},
+		},
+		{
+			name: "formula row inside a rows block",
+			rows: [][]string{
+				{"{{start_process it}}"},
+				append([]string{"{{#rows notes}}{{.Text}}"}, agCell(key + "{{/rows}}")[1:]...),
+			},
+			want: []string{"E1:AF1"},
+		},
+		{
+			name: "empty first block",
+			rows: [][]string{
+				{"{{start_process none}}"},
+				agCell(key),
+				{"{{start_process it}}"},
+				agCell(key),
+			},
+			want: []string{"E1:AF1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			structure := template.New()
+			structure.SetPeriod(period)
+			template.NewEmployeeHandler().
+				Block("sales", domain.GenerateEmployeesForPeriod(2, period)).
+				Block("it", domain.GenerateEmployeesForPeriod(1, period)).
+				Block("none", nil).
+				Register(structure)
+			template.RegisterRowsHandler(structure, "notes", []map[string]any{{"Text": "a"}, {"Text": "b"}})
+
+			formulas := template.New()
+			formulas.SetPeriod(period)
+			template.RegisterFormulaHandler(formulas, 0, template.DetectAttendance, []template.FormulaKey{
+				{Key: key, Formula: template.CountIFExpr("W", 1)},
+			})
+
+			out, err := NewPipeline(structure, formulas).ProcessBytes(templateXLSX(t, tt.rows))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := excelize.OpenReader(bytes.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rows, err := f.GetRows("Sheet1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("result has %d rows, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				cell := fmt.Sprintf("AG%d", i+1)
+				got, err := f.GetCellFormula("Sheet1", cell)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want == "" {
+					if got != "" {
+						t.Errorf("%s = %s, want no formula", cell, got)
+					}
+					continue
+				}
+				if !strings.Contains(got, want) {
+					t.Errorf("%s = %s, want a formula over %s", cell, got, want)
+				}
+			}
+
+			if names := f.GetDefinedName(); len(names) != 0 {
+				t.Errorf("defined names left in the result: %v", names)
+			}
+		})
+	}
+}
+
+// agCell returns a template row holding value in column AG.
+func agCell(value string) []string {
+	row := make([]string, 33)
+	row[32] = value
+	return row
+}
+
+// TestFixedCountTotalFollowsEdits inserts a row above the employee rows after
+// the formula row was written; the total below must still cover them.
+func TestFixedCountTotalFollowsEdits(t *testing.T) {
+	period := domain.MonthPeriod(2026, time.February)
+	data := templateXLSX(t, [][]string{
+		{"1", "Aman", "", "", "W", "W"},
+		{"2", "Maral", "", "", "W"},
+		agCell("{{w}}"),
+		{"{{header}}"},
+		agCell("{{total}}"),
+	})
+
+	r := template.New()
+	r.SetPeriod(period)
+	r.SetEvaluation(template.CachedValues)
+	template.RegisterFormulaHandler(r, 2, template.AttendanceStartCol(0), []template.FormulaKey{
+		{Key: "{{w}}", Formula: template.CountIFExpr("W", 1), ValueFn: template.CountIFValue("W", 1)},
+	})
+	r.Register("{{header}}", func(ctx *template.Context) error {
+		if err := ctx.File.SetCellValue(ctx.Sheet, ctx.Cell(), nil); err != nil {
+			return err
+		}
+		return ctx.InsertRows(0, 1)
+	})
+
+	out, err := New(r).ProcessBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	formula, err := f.GetCellFormula("Sheet1", "AG5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(formula, "SUBTOTAL(9,AG2:AG3)") {
+		t.Errorf("AG5 = %s, want a SUBTOTAL over AG2:AG3", formula)
+	}
+	if v, _ := f.GetCellValue("Sheet1", "AG5"); v != "3" {
+		t.Errorf("AG5 value = %q, want 3", v)
+	}
+}
+
+// TestCancelMidSheet cancels the run from the handler of the cancelAt-th
+// {{n}} cell; the scan must stop there with a *CanceledError naming it.
+func TestCancelMidSheet(t *testing.T) {
+	data := templateXLSX(t, [][]string{
+		{"{{n}}", "{{n}}"},
+		{"{{n}}", "{{n}}"},
+	})
+
+	tests := []struct {
+		name      string
+		cancelAt  int  // 0 cancels before the run
+		keepGoing bool // the run keeps going past cell errors
+		handler   bool // the cancelling handler also fails with ctx.Err()
+		pipeline  bool // run the registry as stage 1 of 2
+		want      CanceledError
+		calls     int
+		prefix    string // error text before the CanceledError's
+	}{
+		{name: "before the first cell", cancelAt: 0, want: CanceledError{}, calls: 0},
+		{name: "mid row", cancelAt: 1, want: CanceledError{Sheet: "Sheet1", Cell: "A1"}, calls: 1},
+		{name: "mid sheet", cancelAt: 3, want: CanceledError{Sheet: "Sheet1", Cell: "A2"}, calls: 3},
+		{name: "keep going", cancelAt: 2, keepGoing: true, want: CanceledError{Sheet: "Sheet1", Cell: "B1"}, calls: 2},
+		{name: "failing handler", cancelAt: 2, keepGoing: true, handler: true, want: CanceledError{Sheet: "Sheet1", Cell: "B1"}, calls: 2},
+		{name: "pipeline", cancelAt: 2, pipeline: true, want: CanceledError{Sheet: "Sheet1", Cell: "B1"}, calls: 2, prefix: "stage 1: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAt == 0 {
+				cancel()
+			}
+
+			calls := 0
+			r := template.New()
+			r.Register("{{n}}", func(hctx *template.Context) error {
+				calls++
+				if calls == tt.cancelAt {
+					cancel()
+					if tt.handler {
+						return hctx.Context().Err()
+					}
+				}
+				return nil
+			})
+
+			var (
+				out []byte
+				err error
+			)
+			if tt.pipeline {
+				p := NewPipeline(r, template.New())
+				p.SetKeepGoing(tt.keepGoing)
+				out, err = p.ProcessBytesContext(ctx, data)
+			} else {
+				p := New(r)
+				p.SetKeepGoing(tt.keepGoing)
+				out, err = p.ProcessBytesContext(ctx, data)
+			}
+
+			if out != nil {
+				t.Error("a cancelled run returned a result")
+			}
+			var canceled *CanceledError
+			if !errors.As(err, &canceled) {
+				t.Fatalf("error = %v, want a *CanceledError", err)
+			}
+			tt.want.Err = context.Canceled
+			if *canceled != tt.want {
+				t.Errorf("CanceledError = %+v, want %+v", *canceled, tt.want)
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Error("error does not unwrap to context.Canceled")
+			}
+			if want := tt.prefix + tt.want.Error(); err.Error() != want {
+				t.Errorf("error = %q, want %q", err, want)
+			}
+			if calls != tt.calls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// workbookFunc processes f in place and returns the errors collected in
+// keep-going mode. Processor and Pipeline both provide one; the helpers below
+// wrap it with the different ways of opening and writing a workbook.
+type workbookFunc func(ctx context.Context, f *excelize.File) (*Errors, error)
+
+// processFile opens input, processes it and returns the result as bytes.
+func processFile(ctx context.Context, input string, fn workbookFunc) ([]byte, error) {
+	f, err := excelize.OpenFile(input)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", input, err)
+	}
+	defer f.Close()
+
+	return writeBytes(ctx, f, fn)
+}
+
+// processBytes opens data, processes it and returns the result as bytes.
+func processBytes(ctx context.Context, data []byte, fn workbookFunc) ([]byte, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	defer f.Close()
+
+	return writeBytes(ctx, f, fn)
+}
+
+// processStream opens the workbook read from r, processes it and writes the
+// result to w.
+func processStream(ctx context.Context, r io.Reader, w io.Writer, fn workbookFunc) error {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return fmt.Errorf("open from reader: %w", err)
+	}
+	defer f.Close()
+
+	errs, err := fn(ctx, f)
+	if err != nil {
+		return err
+	}
+	return write(f, w, errs)
+}
+
+// processToFile opens input, processes it and writes the result to output.
+// A partially written output file is removed.
+func processToFile(ctx context.Context, input, output string, fn workbookFunc) error {
+	f, err := excelize.OpenFile(input)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", input, err)
+	}
+	defer f.Close()
+
+	errs, err := fn(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", output, err)
+	}
+	err = f.Write(out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(output)
+		return errors.Join(fmt.Errorf("write %s: %w", output, err), errs.err())
+	}
+	return errs.err()
+}
+
+// writeBytes processes f and serializes it to bytes. The errors collected in
+// keep-going mode are returned alongside the result.
+func writeBytes(ctx context.Context, f *excelize.File, fn workbookFunc) ([]byte, error) {
+	errs, err := fn(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, errors.Join(fmt.Errorf("write: %w", err), errs.err())
+	}
+	return buf.Bytes(), errs.err()
+}
+
+// write serializes f to w. The errors collected in keep-going mode are
+// returned once the workbook is written.
+func write(f *excelize.File, w io.Writer, errs *Errors) error {
+	if err := f.Write(w); err != nil {
+		return errors.Join(fmt.Errorf("write: %w", err), errs.err())
+	}
+	return errs.err()
+}
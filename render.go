@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/orayew2002/rast-excel/job"
+	"github.com/orayew2002/rast-excel/processor"
+)
+
+// runRender implements `rast-excel render`: it fills the template of a job
+// read from -config, with any flag given on the command line overriding the
+// matching config field.
+func runRender(args []string) int {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to a YAML job file (flags given explicitly override it)")
+	input := fs.String("input", "table.xlsx", "path to the input Excel file")
+	output := fs.String("output", "result.xlsx", "path to the output Excel file")
+	period := fs.String("period", "", "reporting period: YYYY-MM or YYYY-MM-DD..YYYY-MM-DD (default: current month)")
+	holidays := fs.String("holidays", "", "path to a holiday calendar file (one YYYY-MM-DD [name|workday] per line)")
+	weekdays := fs.Bool("weekdays", false, "write weekday abbreviations into the row below the {{days}} header")
+	locale := fs.String("locale", "tk", "weekday abbreviation language: tk, ru, en")
+	weekendFill := fs.String("weekend-fill", "", "hex fill color for rest day and holiday columns (e.g. FFE699)")
+	employees := fs.String("employees", "", "path to an employee roster (.csv, .json, .xlsx); random employees when empty")
+	validate := fs.String("validate", "abort", "roster validation: abort (stop on issues), highlight (mark offending cells) or off")
+	eval := fs.String("eval", "formulas", "formula output: formulas, cached (formulas with values computed in Go) or values")
+	keepGoing := fs.Bool("keep-going", false, "process every cell despite errors, write the result and report all failing cells")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		return 1
+	}
+	if cfg.Input == "" {
+		cfg.Input = *input
+	}
+	if cfg.Output == "" {
+		cfg.Output = *output
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "input":
+			cfg.Input = *input
+		case "output":
+			cfg.Output = *output
+		case "period":
+			cfg.Period = *period
+		case "holidays":
+			cfg.Holidays = *holidays
+		case "weekdays":
+			cfg.Days.Weekdays = *weekdays
+		case "locale":
+			cfg.Days.Locale = *locale
+		case "weekend-fill":
+			cfg.Days.WeekendFill = *weekendFill
+		case "employees":
+			cfg.Employees.Path = *employees
+		case "validate":
+			cfg.Validate = *validate
+		case "eval":
+			cfg.Eval = *eval
+		case "keep-going":
+			cfg.KeepGoing = *keepGoing
+		}
+	})
+
+	j, report, err := job.Prepare(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !report.OK() {
+		fmt.Fprintf(os.Stderr, "employees: %v\n", report.Err())
+	}
+
+	pipeline, err := j.Pipeline()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Ctrl-C stops processing at the next cell instead of killing the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = pipeline.ProcessToFileContext(ctx, cfg.Input, cfg.Output)
+	var cellErrs *processor.Errors
+	if err != nil && !errors.As(err, &cellErrs) {
+		fmt.Fprintf(os.Stderr, "process: %v\n", err)
+		return 1
+	}
+
+	if cellErrs != nil {
+		fmt.Fprintf(os.Stderr, "written with %d error(s): %s\n", len(cellErrs.Cells), cfg.Output)
+		printCellErrors(os.Stderr, cellErrs)
+		return 1
+	}
+
+	fmt.Println("done:", cfg.Output)
+	return 0
+}
+
+// printCellErrors writes the errors collected by -keep-going as a table.
+func printCellErrors(w io.Writer, errs *processor.Errors) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tSHEET\tCELL\tPLACEHOLDER\tERROR")
+	for _, e := range errs.Cells {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%v\n", e.Stage, e.Sheet, orDash(e.Cell), orDash(e.Placeholder), e.Err)
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or "-" for an empty table cell.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/orayew2002/rast-excel/server"
+)
+
+// runServe implements `rast-excel serve`: an HTTP service rendering, inspecting
+// and linting uploaded templates with the stages of a job.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen address")
+	configPath := fs.String("config", "", "path to a YAML job file whose stages every request runs")
+	maxBytes := fs.Int64("max-bytes", 32<<20, "request body size limit in bytes")
+	timeout := fs.Duration("timeout", time.Minute, "time allowed per request")
+	concurrency := fs.Int("concurrency", 0, "requests processed at once (default: number of CPUs)")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		return 1
+	}
+	handler, err := server.New(cfg, server.Options{
+		MaxBytes:      *maxBytes,
+		Timeout:       *timeout,
+		MaxConcurrent: *concurrency,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("listening", "addr", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
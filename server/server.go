@@ -0,0 +1,337 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/job"
+	"github.com/orayew2002/rast-excel/processor"
+	"github.com/orayew2002/rast-excel/template"
+)
+
+// Multipart form parts read by the endpoints.
+const (
+	templatePart = "template" // the .xlsx template
+	dataPart     = "data"     // the employees as a JSON array (see domain.ReadEmployeesJSON)
+)
+
+// xlsxType is the content type of a rendered workbook.
+const xlsxType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Options limits the work a Server accepts. Zero fields take the defaults.
+type Options struct {
+	MaxBytes      int64         // request body size limit (default 32 MiB)
+	Timeout       time.Duration // time allowed per request, including the wait for a slot (default 1 minute)
+	MaxConcurrent int           // requests processed at once (default runtime.NumCPU())
+	Logger        *slog.Logger  // default slog.Default()
+}
+
+// Server is an http.Handler rendering, inspecting and linting uploaded
+// templates. Every request is a multipart form with a "template" part; /render
+// also takes a "data" part with the employees as JSON.
+//
+//	POST /render   template + data → filled .xlsx
+//	POST /inspect  template → processor.Inspection as JSON
+//	POST /lint     template → template.LintReport as JSON
+//
+// Every request runs the stages of one job configuration. Uploads are read
+// into memory and processed with ProcessContext, so nothing touches disk and a
+// request that times out stops processing.
+//
+// Buffering is deliberate: an .xlsx file is a ZIP archive whose directory is
+// at its end, and excelize reads a whole stream into memory before opening it,
+// so streaming the template part would not lower the peak. Memory is bounded
+// instead: a request reads its body only once it holds one of the
+// MaxConcurrent slots, and the body is capped at MaxBytes.
+type Server struct {
+	job  *job.Job
+	opts Options
+	sem  chan struct{}
+	mux  *http.ServeMux
+}
+
+// New creates a Server running the stages of cfg. The input, output and
+// employee source of cfg are ignored: templates and employees come with the
+// requests. cfg is resolved once, holiday file included, so a bad
+// configuration is reported here rather than on every request.
+func New(cfg *job.Config, opts Options) (*Server, error) {
+	j, err := job.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 32 << 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = runtime.NumCPU()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	s := &Server{
+		job:  j,
+		opts: opts,
+		sem:  make(chan struct{}, opts.MaxConcurrent),
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /render", s.handleRender)
+	s.mux.HandleFunc("POST /inspect", s.handleInspect)
+	s.mux.HandleFunc("POST /lint", s.handleLint)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error with the status code it is answered with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+func (e *httpError) Unwrap() error { return e.err }
+
+func statusError(status int, format string, args ...any) error {
+	return &httpError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+func (s *Server) handleRender(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, []string{templatePart, dataPart}, func(ctx context.Context, w http.ResponseWriter, parts map[string][]byte) error {
+		employees, err := domain.ReadEmployeesJSON(bytes.NewReader(parts[dataPart]), domain.LoadOptions{})
+		if err != nil {
+			return statusError(http.StatusBadRequest, "data: %v", err)
+		}
+
+		j := s.job.Clone()
+		j.Employees = employees
+		if _, err := j.Validate(); err != nil {
+			return statusError(http.StatusUnprocessableEntity, "employees: %v", err)
+		}
+
+		pipeline, err := j.Pipeline()
+		if err != nil {
+			return err
+		}
+		// w is buffered by serve and discarded on error, so the workbook
+		// can be streamed into it before processing is known to succeed.
+		w.Header().Set("Content-Type", xlsxType)
+		w.Header().Set("Content-Disposition", `attachment; filename="result.xlsx"`)
+		if err := pipeline.ProcessContext(ctx, bytes.NewReader(parts[templatePart]), w); err != nil {
+			return statusError(http.StatusUnprocessableEntity, "process: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Server) handleInspect(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, []string{templatePart}, func(ctx context.Context, w http.ResponseWriter, parts map[string][]byte) error {
+		pipeline, err := s.job.Clone().Pipeline()
+		if err != nil {
+			return err
+		}
+
+		in, err := pipeline.InspectBytesContext(ctx, parts[templatePart])
+		if err != nil {
+			return statusError(http.StatusUnprocessableEntity, "inspect: %v", err)
+		}
+		return writeJSON(w, http.StatusOK, in)
+	})
+}
+
+func (s *Server) handleLint(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, []string{templatePart}, func(ctx context.Context, w http.ResponseWriter, parts map[string][]byte) error {
+		report, err := processor.LintBytesContext(ctx, parts[templatePart], template.LintOptions{Keys: s.job.FormulaKeys()})
+		if err != nil {
+			return statusError(http.StatusUnprocessableEntity, "lint: %v", err)
+		}
+		return writeJSON(w, http.StatusOK, report)
+	})
+}
+
+// serve waits for a processing slot, reads the required multipart parts of r
+// within the size limit and runs fn, answering with an error when the request
+// is malformed, the server stays busy or fn does not finish in time.
+//
+// The slot is taken before the body is read, so at most MaxConcurrent uploads
+// are held in memory at once; reading the body counts towards the timeout.
+// fn runs in its own goroutine with a context that is cancelled at the
+// timeout; it keeps its slot until it returns.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, required []string, fn func(ctx context.Context, w http.ResponseWriter, parts map[string][]byte) error) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+	defer cancel()
+
+	if err := checkMultipart(r); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		s.fail(w, r, statusError(http.StatusServiceUnavailable, "server busy: %v", ctx.Err()))
+		return
+	}
+	release := func() { <-s.sem }
+
+	// A stalled upload must not keep its slot past the timeout. Not every
+	// ResponseWriter supports deadlines; those rely on the http.Server's.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = http.NewResponseController(w).SetReadDeadline(deadline)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBytes)
+	parts, err := readParts(r, required)
+	if err != nil {
+		release()
+		if ctx.Err() != nil {
+			err = statusError(http.StatusGatewayTimeout, "reading request timed out: %v", ctx.Err())
+		}
+		s.fail(w, r, err)
+		return
+	}
+
+	// The response is buffered, so a handler that finishes after the timeout
+	// cannot write into a response that has already been answered.
+	rec := &bufferedResponse{header: make(http.Header)}
+	done := make(chan error, 1)
+	go func() {
+		defer release()
+		done <- fn(ctx, rec, parts)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			s.fail(w, r, err)
+			return
+		}
+		rec.flush(w)
+	case <-ctx.Done():
+		s.fail(w, r, statusError(http.StatusGatewayTimeout, "processing timed out: %v", ctx.Err()))
+	}
+}
+
+// bufferedResponse collects a response so it can be discarded.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// flush writes the collected response to w.
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
+// fail answers r with err as JSON. Errors without a status are internal.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var herr *httpError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.As(err, &herr):
+		status = herr.status
+	}
+
+	s.opts.Logger.Warn("request failed", "method", r.Method, "path", r.URL.Path, "status", status, "err", err)
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// checkMultipart rejects a request that is not a multipart form.
+func checkMultipart(r *http.Request) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return statusError(http.StatusUnsupportedMediaType, "want a multipart/form-data request")
+	}
+	return nil
+}
+
+// readParts reads the multipart form of r into memory (see Server for why it
+// is not streamed) and checks that every required part is present.
+func readParts(r *http.Request, required []string) (map[string][]byte, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, statusError(http.StatusBadRequest, "multipart: %v", err)
+	}
+
+	parts := make(map[string][]byte)
+	for {
+		p, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, wrapRead(err)
+		}
+
+		data, err := io.ReadAll(p)
+		p.Close()
+		if err != nil {
+			return nil, wrapRead(err)
+		}
+		parts[p.FormName()] = data
+	}
+
+	for _, name := range required {
+		if len(parts[name]) == 0 {
+			return nil, statusError(http.StatusBadRequest, "missing form part %q", name)
+		}
+	}
+	return parts, nil
+}
+
+// wrapRead keeps a body size error for fail and reports anything else as a
+// malformed request.
+func wrapRead(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return err
+	}
+	return statusError(http.StatusBadRequest, "multipart: %v", err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
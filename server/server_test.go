@@ -0,0 +1,274 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orayew2002/rast-excel/job"
+	"github.com/xuri/excelize/v2"
+)
+
+// templateXLSX returns a minimal template with a days header, one employee
+// block and a formula row.
+func templateXLSX(t *testing.T) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for cell, value := range map[string]string{
+		"E1":  "{{days}}",
+		"A2":  "{{start_process}}",
+		"AJ3": "{{num_sum}}",
+	} {
+		if err := f.SetCellValue("Sheet1", cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// employeesJSON returns one employee working 8 hours on every day of
+// February 2026.
+func employeesJSON(t *testing.T) []byte {
+	t.Helper()
+	attendance := make([]string, 28)
+	for i := range attendance {
+		attendance[i] = "8"
+	}
+	data, err := json.Marshal([]map[string]any{{"id": 1, "full_name": "Aman Amanow", "attendance": attendance}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// form encodes parts as a multipart form request to path.
+func form(t *testing.T, path string, parts map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range parts {
+		w, err := mw.CreateFormFile(name, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func newServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	s, err := New(&job.Config{Period: "2026-02"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServeEndpoints(t *testing.T) {
+	tmpl, data := templateXLSX(t), employeesJSON(t)
+
+	tests := []struct {
+		path        string
+		parts       map[string][]byte
+		contentType string
+	}{
+		{"/render", map[string][]byte{templatePart: tmpl, dataPart: data}, xlsxType},
+		{"/inspect", map[string][]byte{templatePart: tmpl}, "application/json"},
+		{"/lint", map[string][]byte{templatePart: tmpl}, "application/json"},
+	}
+
+	s := newServer(t, Options{})
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, form(t, tt.path, tt.parts))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if w.Body.Len() == 0 {
+				t.Error("empty body")
+			}
+		})
+	}
+}
+
+func TestServeRenderResult(t *testing.T) {
+	w := httptest.NewRecorder()
+	newServer(t, Options{}).ServeHTTP(w, form(t, "/render", map[string][]byte{
+		templatePart: templateXLSX(t),
+		dataPart:     employeesJSON(t),
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	f, err := excelize.OpenReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if got, _ := f.GetCellValue("Sheet1", "B2"); got != "Aman Amanow" {
+		t.Errorf("B2 = %q, want the employee's name", got)
+	}
+	if names := f.GetDefinedName(); len(names) != 0 {
+		t.Errorf("result has defined names %v", names)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	tmpl := templateXLSX(t)
+
+	tests := []struct {
+		name   string
+		opts   Options
+		req    func(t *testing.T) *http.Request
+		status int
+	}{
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/lint", strings.NewReader("{}"))
+				r.Header.Set("Content-Type", "application/json")
+				return r
+			},
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "missing template",
+			req: func(t *testing.T) *http.Request {
+				return form(t, "/inspect", map[string][]byte{dataPart: []byte("[]")})
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "missing data",
+			req: func(t *testing.T) *http.Request {
+				return form(t, "/render", map[string][]byte{templatePart: tmpl})
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "bad data",
+			req: func(t *testing.T) *http.Request {
+				return form(t, "/render", map[string][]byte{templatePart: tmpl, dataPart: []byte("{}")})
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "too large",
+			opts: Options{MaxBytes: 512},
+			req: func(t *testing.T) *http.Request {
+				return form(t, "/lint", map[string][]byte{templatePart: tmpl})
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "not a workbook",
+			req: func(t *testing.T) *http.Request {
+				return form(t, "/lint", map[string][]byte{templatePart: []byte("not a zip")})
+			},
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newServer(t, tt.opts).ServeHTTP(w, tt.req(t))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("body = %s, want a JSON error", w.Body)
+			}
+		})
+	}
+}
+
+func TestServeTimeout(t *testing.T) {
+	s := newServer(t, Options{Timeout: 50 * time.Millisecond})
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	w := httptest.NewRecorder()
+	r := form(t, "/lint", map[string][]byte{templatePart: templateXLSX(t)})
+	s.serve(w, r, []string{templatePart}, func(ctx context.Context, w http.ResponseWriter, parts map[string][]byte) error {
+		<-unblock // outlives the timeout
+		return nil
+	})
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504: %s", w.Code, w.Body)
+	}
+}
+
+func TestServeBusy(t *testing.T) {
+	s := newServer(t, Options{Timeout: 50 * time.Millisecond, MaxConcurrent: 1})
+	s.sem <- struct{}{} // the only slot is taken
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, form(t, "/lint", map[string][]byte{templatePart: templateXLSX(t)}))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", w.Code, w.Body)
+	}
+}
+
+func TestNewResolvesConfigOnce(t *testing.T) {
+	if _, err := New(&job.Config{Holidays: filepath.Join(t.TempDir(), "missing.txt")}, Options{}); err == nil {
+		t.Fatal("New with a missing holidays file: want an error")
+	}
+
+	holidays := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(holidays, []byte("2026-02-02 Test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(&job.Config{Period: "2026-02", Holidays: holidays}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Requests must not read the holidays file again.
+	if err := os.Remove(holidays); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/render", "/inspect", "/lint"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, form(t, path, map[string][]byte{
+			templatePart: templateXLSX(t),
+			dataPart:     employeesJSON(t),
+		}))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200: %s", path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Employee blocks ----------
+
+// blockNamePrefix prefixes the sheet-scoped defined names that record where an
+// employee block was written. The name refers to the block's attendance range,
+// so later passes (e.g. RegisterFormulaHandler) can find the employee rows and
+// attendance columns even after rows and columns have shifted — excelize
+// adjusts defined names on every insert and remove.
+const blockNamePrefix = "rast_"
+
+// DefaultBlock is the name of the block written for an unnamed
+// {{start_process}} marker, and by RegisterEmployeeHandler.
+const DefaultBlock = "employees"
+
+// emptyBlockComment marks the defined name of a block without employees. Its
+// range is the row directly above where the block would start.
+const emptyBlockComment = "rast:empty"
+
+// block is the attendance area of one employee block (all 0-based, inclusive).
+// An empty block has lastRow == firstRow-1.
+type block struct {
+	name     string
+	firstRow int
+	lastRow  int
+	attStart int
+	attEnd   int
+}
+
+func (b block) empty() bool { return b.lastRow < b.firstRow }
+
+// defineBlock records b as a sheet-scoped defined name, replacing any earlier
+// definition of the same block. An empty block in the first row is not
+// recorded.
+func defineBlock(f *excelize.File, sheet string, b block) error {
+	name := blockNamePrefix + b.name
+	_ = f.DeleteDefinedName(&excelize.DefinedName{Name: name, Scope: sheet})
+
+	firstRow, comment := b.firstRow, ""
+	if b.empty() {
+		if b.firstRow == 0 {
+			return nil
+		}
+		firstRow, comment = b.lastRow, emptyBlockComment
+	}
+
+	ref := rangeRef(sheet, firstRow, b.attStart, b.lastRow, b.attEnd)
+	dn := &excelize.DefinedName{Name: name, Comment: comment, RefersTo: ref, Scope: sheet}
+	if err := f.SetDefinedName(dn); err != nil {
+		return fmt.Errorf("define block %q: %w", b.name, err)
+	}
+	return nil
+}
+
+// sheetBlocks returns the employee blocks recorded for sheet.
+func sheetBlocks(f *excelize.File, sheet string) []block {
+	var blocks []block
+	for _, dn := range f.GetDefinedName() {
+		if dn.Scope != sheet || !strings.HasPrefix(dn.Name, blockNamePrefix) {
+			continue
+		}
+		b, ok := parseBlockRef(dn.RefersTo)
+		if !ok {
+			continue
+		}
+		b.name = strings.TrimPrefix(dn.Name, blockNamePrefix)
+		if dn.Comment == emptyBlockComment {
+			b.firstRow = b.lastRow + 1
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// findBlock returns the block of sheet with the given name.
+func findBlock(f *excelize.File, sheet, name string) (block, bool) {
+	for _, b := range sheetBlocks(f, sheet) {
+		if b.name == name {
+			return b, true
+		}
+	}
+	return block{}, false
+}
+
+// scopeNamePrefix prefixes the defined names recording the rows bound to an
+// employee block: rastscope_<block> refers to the template rows from below the
+// block's own template rows down to the next {{start_process}} marker. It is
+// recorded before the block is written, so rows inserted inside it later
+// (copies of a {{#rows}} block, other employee rows) widen it and a formula row
+// keeps the block its template placed it under.
+const scopeNamePrefix = "rastscope_"
+
+// defineScope records the rows bound to block name as a sheet-scoped defined
+// name. first is the block's first template row and from the row below its
+// template rows.
+//
+// Earlier scopes reaching into the block's template rows are clipped to end
+// above them first: excelize does not drop a name whose rows are all removed
+// but moves it onto the next row, where it would claim rows of this block.
+func defineScope(f *excelize.File, sheet, name string, first, from int) error {
+	for _, dn := range f.GetDefinedName() {
+		if dn.Scope != sheet || !strings.HasPrefix(dn.Name, scopeNamePrefix) {
+			continue
+		}
+		r, ok := parseBlockRef(dn.RefersTo)
+		if !ok || r.lastRow < first {
+			continue
+		}
+		_ = f.DeleteDefinedName(&excelize.DefinedName{Name: dn.Name, Scope: sheet})
+		if r.firstRow < first {
+			dn.RefersTo = rangeRef(sheet, r.firstRow, 0, first-1, 0)
+			if err := f.SetDefinedName(&dn); err != nil {
+				return fmt.Errorf("clip scope %q: %w", strings.TrimPrefix(dn.Name, scopeNamePrefix), err)
+			}
+		}
+	}
+
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("get rows: %w", err)
+	}
+
+	// The scope ends on the next marker row, or on the row after the last
+	// one, so rows inserted right above either still fall inside it. The next
+	// block clips it when it is written.
+	to := len(rows)
+next:
+	for r := from; r < len(rows); r++ {
+		for _, value := range rows[r] {
+			if strings.Contains(value, "{{"+startMarker) {
+				to = r
+				break next
+			}
+		}
+	}
+
+	dn := &excelize.DefinedName{Name: scopeNamePrefix + name, RefersTo: rangeRef(sheet, from, 0, to, 0), Scope: sheet}
+	if err := f.SetDefinedName(dn); err != nil {
+		return fmt.Errorf("define scope of block %q: %w", name, err)
+	}
+	return nil
+}
+
+// blockOf returns the employee block row is bound to: the block whose scope
+// holds row, looked up by its recorded name. Of overlapping scopes — one moved
+// onto row by a removal — the one starting lowest, then ending lowest, wins.
+// An empty block whose marker was the first row of the sheet has a scope but
+// no recorded range; it is returned as an unnamed empty block.
+func blockOf(f *excelize.File, sheet string, row int) (block, bool) {
+	var (
+		best  block
+		name  string
+		found bool
+	)
+	for _, dn := range f.GetDefinedName() {
+		if dn.Scope != sheet || !strings.HasPrefix(dn.Name, scopeNamePrefix) {
+			continue
+		}
+		r, ok := parseBlockRef(dn.RefersTo)
+		if !ok || row < r.firstRow || row > r.lastRow {
+			continue
+		}
+		if !found || r.firstRow > best.firstRow || (r.firstRow == best.firstRow && r.lastRow > best.lastRow) {
+			best, name, found = r, strings.TrimPrefix(dn.Name, scopeNamePrefix), true
+		}
+	}
+	if !found {
+		return block{}, false
+	}
+	if b, ok := findBlock(f, sheet, name); ok {
+		return b, true
+	}
+	return block{firstRow: 0, lastRow: -1}, true
+}
+
+// rangeRef returns the absolute reference 'Sheet'!$A$1:$B$2 of a 0-based range.
+func rangeRef(sheet string, r1, c1, r2, c2 int) string {
+	return fmt.Sprintf("'%s'!$%s$%d:$%s$%d",
+		strings.ReplaceAll(sheet, "'", "''"),
+		excel.IndexToColumn(c1), r1+1,
+		excel.IndexToColumn(c2), r2+1)
+}
+
+// ---------- Block groups ----------
+
+// groupNamePrefix prefixes the defined names recording the groups of a
+// grouped employee block: rastgrp_<block>_<n>, each referring to the group's
+// employee rows.
+const groupNamePrefix = "rastgrp_"
+
+// subtotalComment marks a group followed by a subtotal row.
+const subtotalComment = "rast:subtotal"
+
+// group is the employee rows of one group of a block (0-based, inclusive).
+// With subtotal set, the row directly below lastRow is its subtotal row.
+type group struct {
+	firstRow int
+	lastRow  int
+	subtotal bool
+}
+
+// defineGroups records the groups of block b, replacing the groups of any
+// earlier definition of the same block.
+func defineGroups(f *excelize.File, sheet string, b block, groups []group) error {
+	for _, dn := range f.GetDefinedName() {
+		if dn.Scope == sheet && isGroupOf(dn.Name, b.name) {
+			_ = f.DeleteDefinedName(&excelize.DefinedName{Name: dn.Name, Scope: sheet})
+		}
+	}
+
+	prefix := groupNamePrefix + b.name + "_"
+	for i, g := range groups {
+		comment := ""
+		if g.subtotal {
+			comment = subtotalComment
+		}
+		dn := &excelize.DefinedName{
+			Name:     fmt.Sprintf("%s%d", prefix, i+1),
+			Comment:  comment,
+			RefersTo: rangeRef(sheet, g.firstRow, b.attStart, g.lastRow, b.attEnd),
+			Scope:    sheet,
+		}
+		if err := f.SetDefinedName(dn); err != nil {
+			return fmt.Errorf("define group %d of block %q: %w", i+1, b.name, err)
+		}
+	}
+	return nil
+}
+
+// isGroupOf reports whether name is rastgrp_<block>_<n>. Block names may
+// hold "_", so the suffix after the block name must be the group number alone:
+// rastgrp_a_b_1 belongs to block "a_b", not to block "a".
+func isGroupOf(name, block string) bool {
+	n, ok := strings.CutPrefix(name, groupNamePrefix+block+"_")
+	if !ok || n == "" {
+		return false
+	}
+	for _, r := range n {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// blockGroups returns the groups recorded for block b, or nil if the block is
+// not grouped.
+func blockGroups(f *excelize.File, sheet string, b block) []group {
+	var groups []group
+	for _, dn := range f.GetDefinedName() {
+		if dn.Scope != sheet || !isGroupOf(dn.Name, b.name) {
+			continue
+		}
+		r, ok := parseBlockRef(dn.RefersTo)
+		if !ok || r.firstRow < b.firstRow || r.lastRow > b.lastRow {
+			continue
+		}
+		groups = append(groups, group{
+			firstRow: r.firstRow,
+			lastRow:  r.lastRow,
+			subtotal: dn.Comment == subtotalComment,
+		})
+	}
+	return groups
+}
+
+// RemoveBlockNames deletes the defined names recording employee blocks, their
+// scopes and their groups. They are bookkeeping for the handlers of later
+// passes and should not reach the finished workbook.
+func RemoveBlockNames(f *excelize.File) {
+	for _, dn := range f.GetDefinedName() {
+		if strings.HasPrefix(dn.Name, blockNamePrefix) || strings.HasPrefix(dn.Name, scopeNamePrefix) ||
+			strings.HasPrefix(dn.Name, groupNamePrefix) {
+			_ = f.DeleteDefinedName(&excelize.DefinedName{Name: dn.Name, Scope: dn.Scope})
+		}
+	}
+}
+
+// parseBlockRef parses "'Sheet'!$E$5:$AF$29" into a block.
+func parseBlockRef(ref string) (block, bool) {
+	if i := strings.LastIndex(ref, "!"); i >= 0 {
+		ref = ref[i+1:]
+	}
+	from, to, ok := strings.Cut(strings.ReplaceAll(ref, "$", ""), ":")
+	if !ok {
+		return block{}, false
+	}
+
+	c1, r1, err := excelize.CellNameToCoordinates(from)
+	if err != nil {
+		return block{}, false
+	}
+	c2, r2, err := excelize.CellNameToCoordinates(to)
+	if err != nil {
+		return block{}, false
+	}
+
+	return block{firstRow: r1 - 1, lastRow: r2 - 1, attStart: c1 - 1, attEnd: c2 - 1}, true
+}
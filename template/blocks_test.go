@@ -0,0 +1,60 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestIsGroupOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		want  bool
+	}{
+		{"rastgrp_a_1", "a", true},
+		{"rastgrp_a_12", "a", true},
+		{"rastgrp_a_b_1", "a", false},
+		{"rastgrp_a_b_1", "a_b", true},
+		{"rastgrp_a_1_2", "a", false},
+		{"rastgrp_a_1", "a_1", false},
+		{"rastgrp_a_", "a", false},
+		{"rast_a", "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := isGroupOf(tt.name, tt.block); got != tt.want {
+			t.Errorf("isGroupOf(%q, %q) = %v, want %v", tt.name, tt.block, got, tt.want)
+		}
+	}
+}
+
+func TestGroupsOfPrefixedBlocks(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	const sheet = "Sheet1"
+
+	// Block "a_b" is written first; block "a" is then defined twice, as a
+	// rewrite of the same block would.
+	ab := block{name: "a_b", firstRow: 0, lastRow: 3, attStart: 4, attEnd: 10}
+	a := block{name: "a", firstRow: 0, lastRow: 3, attStart: 4, attEnd: 10}
+	abGroups := []group{{firstRow: 0, lastRow: 1}, {firstRow: 2, lastRow: 3}}
+	if err := defineGroups(f, sheet, ab, abGroups); err != nil {
+		t.Fatal(err)
+	}
+	if err := defineGroups(f, sheet, a, []group{{firstRow: 0, lastRow: 0}, {firstRow: 1, lastRow: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	aGroups := []group{{firstRow: 0, lastRow: 3, subtotal: true}}
+	if err := defineGroups(f, sheet, a, aGroups); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := blockGroups(f, sheet, ab); !reflect.DeepEqual(got, abGroups) {
+		t.Errorf("groups of a_b = %+v, want %+v", got, abGroups)
+	}
+	if got := blockGroups(f, sheet, a); !reflect.DeepEqual(got, aGroups) {
+		t.Errorf("groups of a = %+v, want %+v", got, aGroups)
+	}
+}
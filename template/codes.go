@@ -0,0 +1,155 @@
+package template
+
+import (
+	"strings"
+
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/formula"
+)
+
+// ---------- Formula keys from attendance codes ----------
+
+// Keys generated by CodeFormulaKeys besides the per-code ones.
+const (
+	workedDaysKey  = "{{worked_days}}"
+	workedHoursKey = "{{worked_hours}}"
+)
+
+// CodeFormulaKeys generates the formula keys of codes. For every code with a
+// Key k:
+//
+//	{{k}}        days with the code: "Y" or "Y 8"
+//	{{k_hours}}  the code's Hours for every "Y" day plus the hours of "Y 8" entries
+//
+// and for the set as a whole:
+//
+//	{{worked_days}}   days with a Worked code or plain hours ("8")
+//	{{worked_hours}}  hours of the Worked codes plus plain hours
+//
+// Every key has a ValueFn, so the keys work in every Evaluation mode.
+func CodeFormulaKeys(codes *domain.Codes) []FormulaKey {
+	var keys []FormulaKey
+	var worked []domain.Code
+	for _, code := range codes.All() {
+		if code.Worked {
+			worked = append(worked, code)
+		}
+		if code.Key == "" {
+			continue
+		}
+		keys = append(keys,
+			FormulaKey{
+				Key:     "{{" + code.Key + "}}",
+				Formula: codeDaysExpr(code.Symbol),
+				ValueFn: codeDaysValue(code.Symbol),
+			},
+			FormulaKey{
+				Key:     "{{" + code.Key + "_hours}}",
+				Formula: codeHoursExpr(code),
+				ValueFn: codeHoursValue(code),
+			},
+		)
+	}
+
+	return append(keys,
+		FormulaKey{
+			Key: workedDaysKey,
+			Formula: func(attRange formula.Expr) formula.Expr {
+				parts := []formula.Expr{plainDaysExpr(attRange)}
+				for _, code := range worked {
+					parts = append(parts, codeDaysExpr(code.Symbol)(attRange))
+				}
+				return sumExprs(parts)
+			},
+			ValueFn: func(attendance []string) float64 {
+				sum := plainDaysValue(attendance)
+				for _, code := range worked {
+					sum += codeDaysValue(code.Symbol)(attendance)
+				}
+				return sum
+			},
+		},
+		FormulaKey{
+			Key: workedHoursKey,
+			Formula: func(attRange formula.Expr) formula.Expr {
+				parts := []formula.Expr{HoursExpr("")(attRange)}
+				for _, code := range worked {
+					parts = append(parts, codeHoursExpr(code)(attRange))
+				}
+				return sumExprs(parts)
+			},
+			ValueFn: func(attendance []string) float64 {
+				sum := HoursValue("")(attendance)
+				for _, code := range worked {
+					sum += codeHoursValue(code)(attendance)
+				}
+				return sum
+			},
+		},
+	)
+}
+
+// codeDaysExpr counts the cells holding symbol alone or with hours.
+func codeDaysExpr(symbol string) func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		prefix := symbol + " "
+		withHours := formula.Eq(left(attRange, formula.Int(len([]rune(prefix)))), formula.Str(prefix))
+		return formula.SumProduct(formula.Add(formula.Eq(attRange, formula.Str(symbol)), withHours))
+	}
+}
+
+func codeDaysValue(symbol string) func([]string) float64 {
+	return func(attendance []string) float64 {
+		n := 0
+		for _, s := range attendance {
+			if _, ok := cutPrefixFold(s, symbol+" "); ok || strings.EqualFold(s, symbol) {
+				n++
+			}
+		}
+		return float64(n)
+	}
+}
+
+// codeHoursExpr sums code.Hours over the bare code cells and the hours of the
+// code cells that carry their own.
+func codeHoursExpr(code domain.Code) func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		explicit := HoursExpr(code.Symbol)(attRange)
+		if code.Hours == 0 {
+			return explicit
+		}
+		bare := formula.SumProduct(formula.Mul(formula.Eq(attRange, formula.Str(code.Symbol)), formula.Num(code.Hours)))
+		return formula.Add(bare, explicit)
+	}
+}
+
+func codeHoursValue(code domain.Code) func([]string) float64 {
+	return func(attendance []string) float64 {
+		bare := CountIFValue(code.Symbol, 1)(attendance)
+		return bare*code.Hours + HoursValue(code.Symbol)(attendance)
+	}
+}
+
+// plainDaysExpr counts the plain hour entries ("8", "4/2").
+func plainDaysExpr(attRange formula.Expr) formula.Expr {
+	cond, _ := hoursPart(attRange, "")
+	return formula.SumProduct(formula.Mul(cond, formula.Int(1)))
+}
+
+func plainDaysValue(attendance []string) float64 {
+	n := 0
+	for _, s := range attendance {
+		if s != "" && s[0] >= '0' && s[0] <= '9' {
+			n++
+		}
+	}
+	return float64(n)
+}
+
+// sumExprs adds parts, which may hold a single expression.
+func sumExprs(parts []formula.Expr) formula.Expr {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return formula.Add(parts...)
+}
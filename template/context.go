@@ -0,0 +1,245 @@
+package template
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/orayew2002/rast-excel/calendar"
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Context ----------
+
+// Context describes one matched cell and the run it belongs to.
+//
+// File, Sheet, Row and Col (0-based) locate the cell, Value is its raw value
+// and Match tells how the registered pattern matched. Period, Calendar and
+// Evaluation come from the registry.
+//
+// Styles, Store and Logger are shared by every handler of a processing run —
+// across cells, sheets and pipeline stages — so handlers keep caches and
+// cross-cell state there rather than in their own fields.
+//
+// Handlers that insert or remove rows or columns must do so through the
+// Context methods (InsertRows, RemoveRows, DuplicateRowTo, InsertCols,
+// RemoveCols) rather than on File directly: the processor uses the recorded
+// edits to keep scanning the sheet at the right coordinates.
+type Context struct {
+	File  *excelize.File
+	Sheet string
+	Row   int
+	Col   int
+	Value string
+	Match Match
+
+	Styles     *StyleManager
+	Period     domain.Period
+	Calendar   *calendar.Calendar
+	Evaluation Evaluation
+	Logger     *slog.Logger
+	Store      *Store
+
+	run *Run
+}
+
+// Context returns the context of the processing run. Long-running handlers
+// should stop and return its error once it is done.
+func (c *Context) Context() context.Context {
+	if c.run == nil {
+		return context.Background()
+	}
+	return c.run.Context()
+}
+
+// Cell returns the A1-style name of the matched cell.
+func (c *Context) Cell() string {
+	return excel.CellName(c.Row, c.Col)
+}
+
+// Run is the state shared by all handlers while one workbook is processed.
+// A Pipeline uses one Run for all of its stages.
+type Run struct {
+	File   *excelize.File
+	Styles *StyleManager
+	Store  *Store
+
+	ctx   context.Context
+	edits []Edit
+}
+
+// NewRun starts a processing run over f.
+func NewRun(f *excelize.File) *Run {
+	return NewRunContext(context.Background(), f)
+}
+
+// NewRunContext starts a processing run over f that handlers can cancel
+// through ctx.
+func NewRunContext(ctx context.Context, f *excelize.File) *Run {
+	return &Run{
+		File:   f,
+		Styles: NewStyleManager(f),
+		Store:  NewStore(),
+		ctx:    ctx,
+	}
+}
+
+// Context returns the context the run was started with.
+func (r *Run) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// Edits returns the structural edits made through handler contexts so far,
+// in order.
+func (r *Run) Edits() []Edit {
+	return r.edits
+}
+
+// ---------- Structural edits ----------
+
+// Axis selects rows or columns.
+type Axis int
+
+const (
+	Rows Axis = iota
+	Cols
+)
+
+// Edit is a structural change made by a handler: N rows or columns inserted
+// (N > 0) or removed (N < 0) at the 0-based index At of Sheet.
+type Edit struct {
+	Sheet string
+	Axis  Axis
+	At    int
+	N     int
+}
+
+// Shift maps a 0-based row or column index on e's axis through the edit.
+// ok is false when the index itself was removed; idx is then the first index
+// after the removed range.
+func (e Edit) Shift(idx int) (shifted int, ok bool) {
+	switch {
+	case e.N > 0 && idx >= e.At:
+		return idx + e.N, true
+	case e.N < 0 && idx >= e.At-e.N:
+		return idx + e.N, true
+	case e.N < 0 && idx >= e.At:
+		return e.At, false
+	}
+	return idx, true
+}
+
+// InsertRows inserts n empty rows before the 0-based row at.
+func (c *Context) InsertRows(at, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := c.File.InsertRows(c.Sheet, at+1, n); err != nil {
+		return err
+	}
+	c.record(Rows, at, n)
+	return nil
+}
+
+// RemoveRows removes n rows starting at the 0-based row at.
+func (c *Context) RemoveRows(at, n int) error {
+	for i := range n {
+		if err := c.File.RemoveRow(c.Sheet, at+1); err != nil {
+			c.record(Rows, at, -i)
+			return err
+		}
+	}
+	c.record(Rows, at, -n)
+	return nil
+}
+
+// DuplicateRowTo copies the 0-based row src, with its values and styles,
+// into a new row inserted before the 0-based row dst.
+func (c *Context) DuplicateRowTo(src, dst int) error {
+	if err := c.File.DuplicateRowTo(c.Sheet, src+1, dst+1); err != nil {
+		return err
+	}
+	c.record(Rows, dst, 1)
+	return nil
+}
+
+// InsertCols inserts n empty columns before the 0-based column at.
+func (c *Context) InsertCols(at, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := c.File.InsertCols(c.Sheet, excel.IndexToColumn(at), n); err != nil {
+		return err
+	}
+	c.record(Cols, at, n)
+	return nil
+}
+
+// RemoveCols removes n columns starting at the 0-based column at.
+func (c *Context) RemoveCols(at, n int) error {
+	for i := range n {
+		if err := c.File.RemoveCol(c.Sheet, excel.IndexToColumn(at)); err != nil {
+			c.record(Cols, at, -i)
+			return err
+		}
+	}
+	c.record(Cols, at, -n)
+	return nil
+}
+
+// edits returns the structural edits of the run so far.
+func (c *Context) edits() []Edit {
+	if c.run == nil {
+		return nil
+	}
+	return c.run.edits
+}
+
+func (c *Context) record(axis Axis, at, n int) {
+	if c.run == nil || n == 0 {
+		return
+	}
+	c.run.edits = append(c.run.edits, Edit{Sheet: c.Sheet, Axis: axis, At: at, N: n})
+}
+
+// Store is a key/value store that lives for one processing run.
+// Handlers should prefix their keys with their own name
+// (e.g. "border:topLeft:Sheet1") to stay clear of each other.
+type Store struct {
+	values map[string]any
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{values: make(map[string]any)}
+}
+
+// Get returns the value stored under key.
+func (s *Store) Get(key string) (any, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set stores value under key, replacing any earlier value.
+func (s *Store) Set(key string, value any) {
+	s.values[key] = value
+}
+
+// Delete removes key from the store.
+func (s *Store) Delete(key string) {
+	delete(s.values, key)
+}
+
+// Once reports whether key is seen for the first time in the run, recording
+// it. Handlers use it to do per-row or per-sheet work exactly once.
+func (s *Store) Once(key string) bool {
+	if _, ok := s.values[key]; ok {
+		return false
+	}
+	s.values[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,108 @@
+package template
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Formula evaluation ----------
+
+// Evaluation selects whether formula handlers compute their results in Go
+// from the attendance cells, for readers that cannot calculate formulas.
+type Evaluation int
+
+const (
+	// FormulasOnly writes formulas without values; Excel computes them on open.
+	FormulasOnly Evaluation = iota
+	// CachedValues writes formulas with the result computed in Go stored as
+	// their cached value.
+	CachedValues
+	// ValuesOnly writes the result computed in Go instead of a formula.
+	ValuesOnly
+)
+
+// CountIFValue is the Go counterpart of CountIFFormula: it counts occurrences
+// of symbol in attendance, multiplied by value. Like Excel's "=", it ignores
+// case: "w" counts as "W".
+func CountIFValue(symbol string, value int) func([]string) float64 {
+	return func(attendance []string) float64 {
+		n := 0
+		for _, a := range attendance {
+			if strings.EqualFold(a, symbol) {
+				n++
+			}
+		}
+		return float64(n * value)
+	}
+}
+
+// SumNumValue is the Go counterpart of SumNumFormula: it sums the numeric
+// entries of attendance.
+func SumNumValue() func([]string) float64 {
+	return func(attendance []string) float64 {
+		var sum float64
+		for _, a := range attendance {
+			if v, ok := excelNumber(a); ok {
+				sum += v
+			}
+		}
+		return sum
+	}
+}
+
+// CountNumValue is the Go counterpart of CountNumFormula: it counts the
+// numeric entries of attendance.
+func CountNumValue() func([]string) float64 {
+	return func(attendance []string) float64 {
+		n := 0
+		for _, a := range attendance {
+			if _, ok := excelNumber(a); ok {
+				n++
+			}
+		}
+		return float64(n)
+	}
+}
+
+// excelNumber converts s the way Excel's VALUE does for the attendance codes
+// written by this package. Attendance cells hold text, so an empty code is
+// not a number, just as VALUE("") is an error. ParseFloat also reads "NaN",
+// "Inf" and hex floats such as "0x1p3", which VALUE rejects and which must
+// not reach a cell.
+func excelNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "xX") {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// setResult writes formula and its computed value into cell according to mode.
+// A zero value leaves the cell empty, matching blankZero. ok is false
+// when the value could not be computed; then only the formula is written.
+func setResult(f *excelize.File, sheet, cell, formula string, value float64, ok bool, mode Evaluation) error {
+	if mode == FormulasOnly || !ok {
+		return f.SetCellFormula(sheet, cell, formula)
+	}
+
+	if value == 0 {
+		if err := f.SetCellValue(sheet, cell, nil); err != nil {
+			return err
+		}
+	} else if err := f.SetCellFloat(sheet, cell, value, -1, 64); err != nil {
+		return err
+	}
+
+	if mode == ValuesOnly {
+		return nil
+	}
+	// SetCellFormula keeps the value written above as the cached result.
+	return f.SetCellFormula(sheet, cell, formula)
+}
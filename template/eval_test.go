@@ -0,0 +1,154 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/orayew2002/rast-excel/formula"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestFormulaMatchesValueFn runs attendance rows through each formula in
+// excelize's calculation engine and through its Go counterpart, which must
+// agree for cached values to match what Excel shows.
+func TestFormulaMatchesValueFn(t *testing.T) {
+	rows := [][]string{
+		{"8", "8/2", "8+1.5", "W", ""},
+		{"Y 8", "Y 4/2+1", "8", "B", "Y"},
+		{"4,5", "4.5", "8/2+1", "Y8", "Y 4,5"},
+		{"0", "10/0", "Y 0", "IWI 2", "8+"},
+	}
+
+	keys := []struct {
+		name    string
+		expr    func(formula.Expr) formula.Expr
+		valueFn func([]string) float64
+	}{
+		{"hours", HoursExpr(""), HoursValue("")},
+		{"night_hours", NightHoursExpr(""), NightHoursValue("")},
+		{"overtime", OvertimeExpr(""), OvertimeValue("")},
+		{"hour_entries", HourEntriesExpr(""), HourEntriesValue("")},
+		{"y_hours", HoursExpr("Y"), HoursValue("Y")},
+		{"y_night", NightHoursExpr("Y"), NightHoursValue("Y")},
+		{"y_overtime", OvertimeExpr("Y"), OvertimeValue("Y")},
+		{"y_entries", HourEntriesExpr("Y"), HourEntriesValue("Y")},
+		{"count_y", CountIFExpr("Y", 1), CountIFValue("Y", 1)},
+		{"count_8", CountIFExpr("8", 8), CountIFValue("8", 8)},
+		{"count_w", CountIFExpr("W", 1), CountIFValue("W", 1)},
+		{"y_days", codeDaysExpr("Y"), codeDaysValue("Y")},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	const sheet = "Sheet1"
+
+	for i, row := range rows {
+		r := i + 1
+		for c, v := range row {
+			cell, _ := excelize.CoordinatesToCellName(c+1, r)
+			if err := f.SetCellStr(sheet, cell, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// excelize evaluates the text functions cell by cell only, so each
+	// formula is applied to one cell at a time. For one cell SUMPRODUCT(x) is
+	// x; the wrapper is dropped because excelize mis-parses an error-valued
+	// argument nested inside another function's arguments.
+	for _, k := range keys {
+		for i, row := range rows {
+			r := i + 1
+			var excel float64
+			for c := range row {
+				ref, _ := excelize.CoordinatesToCellName(c+1, r)
+				text, err := formula.Render(k.expr(formula.Ref(ref)))
+				if err != nil {
+					t.Fatalf("%s: %v", k.name, err)
+				}
+				text = strings.TrimSuffix(strings.TrimPrefix(text, "SUMPRODUCT("), ")")
+
+				out, _ := excelize.CoordinatesToCellName(len(row)+2, r)
+				if err := f.SetCellFormula(sheet, out, text); err != nil {
+					t.Fatal(err)
+				}
+				got, err := f.CalcCellValue(sheet, out)
+				if err != nil {
+					t.Fatalf("%s %q: calc %s: %v", k.name, row[c], text, err)
+				}
+				v, err := strconv.ParseFloat(got, 64)
+				if err != nil {
+					t.Fatalf("%s %q: %s = %q, want a number", k.name, row[c], text, got)
+				}
+				if want := k.valueFn(row[c : c+1]); v != want {
+					t.Errorf("%s %q: formula gives %v, ValueFn gives %v\n%s", k.name, row[c], v, want, text)
+				}
+				excel += v
+			}
+
+			if want := k.valueFn(row); excel != want {
+				t.Errorf("%s row %q: formula gives %v, ValueFn gives %v", k.name, row, excel, want)
+			}
+		}
+	}
+}
+
+func TestExcelNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"8", 8, true},
+		{" 4.5 ", 4.5, true},
+		{"-2", -2, true},
+		{"1e2", 100, true},
+		{"", 0, false},
+		{"W", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+		{"-infinity", 0, false},
+		{"1e999", 0, false},
+		{"0x1p3", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := excelNumber(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("excelNumber(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got := SumNumValue()([]string{"8", "NaN", "Inf", "-Inf"}); got != 8 {
+		t.Errorf("SumNumValue = %v, want 8", got)
+	}
+}
+
+// TestValueFnIgnoresCase checks the Go values against Excel's comparisons,
+// which ignore case. excelize compares case-sensitively, so these cannot go
+// through TestFormulaMatchesValueFn.
+func TestValueFnIgnoresCase(t *testing.T) {
+	attendance := []string{"w", "W", "y 8", "Y 4/2+1", "ýs 2", "y", "yy 8"}
+
+	tests := []struct {
+		name    string
+		valueFn func([]string) float64
+		want    float64
+	}{
+		{"count W", CountIFValue("W", 1), 2},
+		{"count Y", CountIFValue("Y", 1), 1},
+		{"days Y", codeDaysValue("Y"), 3},
+		{"days ÝS", codeDaysValue("ÝS"), 1},
+		{"hours Y", HoursValue("Y"), 12},
+		{"hours ÝS", HoursValue("ÝS"), 2},
+		{"night Y", NightHoursValue("Y"), 2},
+		{"entries Y", HourEntriesValue("Y"), 2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.valueFn(attendance); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Employee groups ----------
+
+// Group row markers and placeholders. A {{group_header}} row directly above
+// the {{start_process … group=Field}} row is repeated before every group; a
+// {{group_subtotal}} row directly below it is repeated after every group.
+const (
+	groupHeaderMarker   = "{{group_header}}"
+	groupSubtotalMarker = "{{group_subtotal}}"
+	groupValueKey       = "{{group}}"
+)
+
+// groupOption is the marker argument naming the group-by field.
+const groupOption = "group"
+
+// employeeGroup is a run of employees sharing the group-by field value.
+// index holds the position of each employee in the block's list.
+type employeeGroup struct {
+	value     string
+	employees []domain.Employee
+	index     []int
+}
+
+// ungrouped puts all employees into one group.
+func ungrouped(employees []domain.Employee) employeeGroup {
+	g := employeeGroup{employees: employees, index: make([]int, len(employees))}
+	for i := range g.index {
+		g.index[i] = i
+	}
+	return g
+}
+
+// groupEmployees splits employees by the value of field, in order of first
+// appearance. Employees keep their relative order within a group.
+func groupEmployees(employees []domain.Employee, field string) []employeeGroup {
+	var groups []employeeGroup
+	index := make(map[string]int)
+	for j, emp := range employees {
+		value := employeeField(emp, field)
+		i, ok := index[value]
+		if !ok {
+			i = len(groups)
+			index[value] = i
+			groups = append(groups, employeeGroup{value: value})
+		}
+		groups[i].employees = append(groups[i].employees, emp)
+		groups[i].index = append(groups[i].index, j)
+	}
+	return groups
+}
+
+// renderGroupCell renders a header or subtotal cell for g: {{group}} becomes
+// the group value, {{.Field}} the field of the group's first employee.
+func renderGroupCell(tmpl string, g employeeGroup) string {
+	tmpl = strings.ReplaceAll(tmpl, groupHeaderMarker, "")
+	tmpl = strings.ReplaceAll(tmpl, groupSubtotalMarker, "")
+	tmpl = strings.ReplaceAll(tmpl, groupValueKey, g.value)
+	return rowsFieldPat.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		if len(g.employees) == 0 {
+			return ""
+		}
+		return employeeField(g.employees[0], rowsFieldPat.FindStringSubmatch(placeholder)[1])
+	})
+}
+
+// rowTemplate is a snapshot of one template row: values, styles, height and
+// single-row merges, so the row can be written again after it is removed.
+type rowTemplate struct {
+	cells  []string
+	styles []int
+	height float64
+	merges [][2]int // c1, c2 (0-based)
+}
+
+// readRowTemplate captures row if any of its cells contains marker.
+// It returns nil when the row does not exist or holds no marker.
+func readRowTemplate(f *excelize.File, sheet string, row int, marker string) (*rowTemplate, error) {
+	if row < 0 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("get rows: %w", err)
+	}
+	if row >= len(rows) || !strings.Contains(strings.Join(rows[row], ""), marker) {
+		return nil, nil
+	}
+
+	t := &rowTemplate{cells: append([]string(nil), rows[row]...)}
+
+	merges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("get merges: %w", err)
+	}
+	width := len(t.cells)
+	for _, mc := range merges {
+		c1, r1, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		c2, r2, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		if r1-1 == row && r2-1 == row {
+			t.merges = append(t.merges, [2]int{c1 - 1, c2 - 1})
+			width = max(width, c2)
+		}
+	}
+
+	for c := range width {
+		styleID, _ := f.GetCellStyle(sheet, excel.CellName(row, c))
+		t.styles = append(t.styles, styleID)
+	}
+	if t.height, err = f.GetRowHeight(sheet, row+1); err != nil {
+		return nil, fmt.Errorf("row height: %w", err)
+	}
+
+	return t, nil
+}
+
+// write renders the template into row, which must be empty.
+func (t *rowTemplate) write(f *excelize.File, sheet string, row int, render func(string) string) error {
+	for c, styleID := range t.styles {
+		cell := excel.CellName(row, c)
+		if c < len(t.cells) && t.cells[c] != "" {
+			if err := f.SetCellStr(sheet, cell, render(t.cells[c])); err != nil {
+				return fmt.Errorf("cell %s: %w", cell, err)
+			}
+		}
+		if styleID != 0 {
+			if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+				return fmt.Errorf("style %s: %w", cell, err)
+			}
+		}
+	}
+
+	for _, m := range t.merges {
+		topLeft, bottomRight := excel.CellName(row, m[0]), excel.CellName(row, m[1])
+		if err := f.MergeCell(sheet, topLeft, bottomRight); err != nil {
+			return fmt.Errorf("merge %s:%s: %w", topLeft, bottomRight, err)
+		}
+	}
+
+	return f.SetRowHeight(sheet, row+1, t.height)
+}
@@ -5,104 +5,441 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 	"unicode"
 
+	"github.com/orayew2002/rast-excel/calendar"
 	"github.com/orayew2002/rast-excel/domain"
 	"github.com/orayew2002/rast-excel/excel"
+	"github.com/orayew2002/rast-excel/formula"
 	"github.com/xuri/excelize/v2"
 )
 
 // RegisterDefaults registers the built-in template handlers (days, working_time).
 func RegisterDefaults(r *Registry) {
-	r.Register("{{days}}", handleDays)
-	r.Register("{{working_time}}", handleWorkingTime)
+	r.register("days", "{{days}}", func(ctx *Context) error {
+		return handleDays(ctx, r.days)
+	})
+	r.register("working_time", "{{working_time}}", handleWorkingTime)
 }
 
 // ---------- Employee columns ----------
 
-// columnDef describes one fixed employee column: value extractor + style.
-type columnDef struct {
-	value func(emp domain.Employee) string
-	style func(sm *StyleManager) (int, error)
-}
-
-// columns defines the fixed employee columns in order.
-// To add a new column: append one entry here — that's it.
-var columns = []columnDef{
-	{value: func(e domain.Employee) string { return strconv.Itoa(e.Id) }, style: (*StyleManager).Centered},
-	{value: func(e domain.Employee) string { return e.FullName }, style: (*StyleManager).Centered},
-	{value: func(e domain.Employee) string { return e.TableID }, style: (*StyleManager).Centered},
-	{value: func(e domain.Employee) string { return e.JobPosition }, style: (*StyleManager).Centered},
+// defaultFields are the employee columns written when the {{start_process}}
+// row declares no {{.Field}} placeholders of its own.
+var defaultFields = []string{
+	domain.FieldID,
+	domain.FieldFullName,
+	domain.FieldTableID,
+	domain.FieldJobPosition,
 }
 
 // AttendanceStartCol returns the 0-based column index where attendance data begins
-// for an employee section that starts at employeeCol.
+// for an employee section that starts at employeeCol and uses the default columns.
+// Templates that declare their own columns should pass DetectAttendance to
+// RegisterFormulaHandler instead.
 func AttendanceStartCol(employeeCol int) int {
-	return employeeCol + len(columns)
+	return employeeCol + len(defaultFields)
+}
+
+// layoutField is one employee column: a cell template such as "{{.FullName}}"
+// rendered per employee, and the style copied from the template cell.
+type layoutField struct {
+	col   int
+	tmpl  string
+	style int // 0 = centered
+}
+
+// employeeLayout is the column layout of an employee block.
+type employeeLayout struct {
+	fields   []layoutField
+	attStart int // 0-based column where attendance begins
+	attStyle int // 0 = centered
+}
+
+// readEmployeeLayout reads the column layout declared in the {{start_process}}
+// row. Cells holding {{.Field}} placeholders become columns; the cell holding
+// {{attendance}} marks where attendance begins (default: the column after the
+// last field). Without placeholders the default columns start at col.
+func readEmployeeLayout(f *excelize.File, sheet string, row, col int) (employeeLayout, error) {
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return employeeLayout{}, fmt.Errorf("get rows: %w", err)
+	}
+
+	var layout employeeLayout
+	attCol := -1
+	if row < len(rows) {
+		for c, value := range rows[row] {
+			cell := excel.CellName(row, c)
+			if strings.Contains(value, "{{attendance}}") {
+				attCol = c
+				layout.attStyle, _ = f.GetCellStyle(sheet, cell)
+			}
+			if !rowsFieldPat.MatchString(value) {
+				continue
+			}
+			styleID, _ := f.GetCellStyle(sheet, cell)
+			layout.fields = append(layout.fields, layoutField{col: c, tmpl: value, style: styleID})
+		}
+	}
+
+	if len(layout.fields) == 0 {
+		for i, name := range defaultFields {
+			layout.fields = append(layout.fields, layoutField{col: col + i, tmpl: "{{." + name + "}}"})
+		}
+	}
+
+	layout.attStart = attCol
+	if attCol < 0 {
+		layout.attStart = layout.fields[len(layout.fields)-1].col + 1
+	}
+
+	return layout, nil
+}
+
+// employeeField returns the value of the named employee field. Names other
+// than the built-in fields are looked up in Employee.Fields (exact match
+// first, then case-insensitive); a missing extra field is empty.
+func employeeField(emp domain.Employee, name string) string {
+	switch name {
+	case domain.FieldID:
+		return strconv.Itoa(emp.Id)
+	case domain.FieldFullName:
+		return emp.FullName
+	case domain.FieldTableID:
+		return emp.TableID
+	case domain.FieldJobPosition:
+		return emp.JobPosition
+	}
+
+	if v, ok := emp.Fields[name]; ok {
+		return v
+	}
+	for k, v := range emp.Fields {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// renderEmployeeCell renders a field cell template for emp, dropping the
+// block markers.
+func renderEmployeeCell(tmpl, marker string, emp domain.Employee) string {
+	tmpl = strings.ReplaceAll(tmpl, marker, "")
+	tmpl = strings.ReplaceAll(tmpl, "{{attendance}}", "")
+	return rowsFieldPat.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		return employeeField(emp, rowsFieldPat.FindStringSubmatch(placeholder)[1])
+	})
 }
 
 // ---------- RegisterEmployeeHandler ----------
 
+// startMarker is the placeholder name of an employee block marker.
+const startMarker = "start_process"
+
 // RegisterEmployeeHandler registers the {{start_process}} handler.
 // It writes employee rows (fixed columns + attendance) into the sheet,
 // replacing the template row. No formulas are written here — use
 // RegisterFormulaHandler in a second pass for that.
+//
+// The template row may declare its own columns with {{.Field}} placeholders
+// (Id, FullName, TableID, JobPosition or any key of Employee.Fields) and mark
+// the first attendance column with {{attendance}}:
+//
+//	| {{start_process}}{{.Id}} | {{.FullName}} | {{.Department}} | {{attendance}} |
+//
+// Without placeholders the default columns (Id, FullName, TableID,
+// JobPosition) are written starting at the {{start_process}} cell. The
+// resulting attendance range is recorded in the workbook so the formula pass
+// can find it (see DetectAttendance).
+//
+// When the registry's DaysOptions set a WeekendFill, attendance cells of rest
+// days and holidays get the same fill as their {{days}} header column.
+//
+// For several blocks per sheet, use NewEmployeeHandler.
 func RegisterEmployeeHandler(r *Registry, employees []domain.Employee) {
-	r.Register("{{start_process}}", func(f *excelize.File, sheet string, row, col int, _ string) error {
-		return writeEmployees(f, sheet, row, col, employees)
+	NewEmployeeHandler().Block(DefaultBlock, employees).Register(r)
+}
+
+// EmployeeHandler writes named employee blocks, each fed its own employee
+// list — e.g. one block per department on the same sheet:
+//
+//	| {{start_process dept=sales}} |   ← rows of the "sales" block
+//	| {{t}} | {{w}} |                  ← formulas for the sales block
+//	| {{start_process dept=it}} |      ← rows of the "it" block
+//	| {{t}} | {{w}} |                  ← formulas for the it block
+//
+// The block name is the first marker argument, with an optional key= prefix
+// ({{start_process sales}} and {{start_process dept=sales}} both name
+// "sales"). An unnamed {{start_process}} is the block RegisterEmployeeHandler
+// writes. Names may hold letters, digits, "_" and ".".
+//
+// Every block is written as described for RegisterEmployeeHandler. A block
+// with no employees removes its template row.
+//
+// A group=Field argument groups the block's employees by Field (a built-in
+// field or a key of Employee.Fields), in order of first appearance:
+//
+//	| {{group_header}}{{group}}                     |   ← once before every group
+//	| {{start_process dept=all group=Department}}   |   ← employees of the group
+//	| {{group_subtotal}}Jemi                        |   ← once after every group
+//
+// The header and subtotal rows are optional and must sit directly above and
+// below the marker row; their cells may use {{group}} (the group value) and
+// {{.Field}} (taken from the group's first employee), and keep the template
+// styles and merges. With DetectAttendance the formula pass writes SUBTOTAL
+// formulas over each group's rows into its subtotal row.
+//
+// Usage:
+//
+//	template.NewEmployeeHandler().
+//	    Block("sales", sales).
+//	    Block("it", it).
+//	    Register(registry)
+type EmployeeHandler struct {
+	blocks    map[string][]domain.Employee
+	highlight *highlight
+}
+
+// NewEmployeeHandler creates an EmployeeHandler with no blocks.
+func NewEmployeeHandler() *EmployeeHandler {
+	return &EmployeeHandler{blocks: make(map[string][]domain.Employee)}
+}
+
+// Block binds the block name to employees. Returns h so calls can be chained.
+func (h *EmployeeHandler) Block(name string, employees []domain.Employee) *EmployeeHandler {
+	h.blocks[name] = employees
+	return h
+}
+
+// Highlight validates every block before it is written (see
+// domain.ValidateEmployees) and fills the offending cells with color, a hex
+// fill such as "FF9999": the attendance cell of a bad entry, the field cell of
+// a missing name or duplicate table ID, and the whole attendance range of a
+// length mismatch. Without opts.Period the registry period is used.
+// Returns h so calls can be chained.
+func (h *EmployeeHandler) Highlight(opts domain.ValidateOptions, color string) *EmployeeHandler {
+	h.highlight = &highlight{opts: opts, color: color}
+	return h
+}
+
+// Register registers h into r for {{start_process …}} markers. The column
+// and group row placeholders the handler reads around a marker are claimed.
+func (h *EmployeeHandler) Register(r *Registry) {
+	r.Claim("{{attendance}}")
+	r.Claim(groupHeaderMarker)
+	r.Claim(groupSubtotalMarker)
+	r.Claim(groupValueKey)
+	r.ClaimRegexp(rowsFieldPat)
+	r.registerPlaceholder("employees", startMarker, func(ctx *Context) error {
+		marker := Placeholder{Text: ctx.Match.Text, Name: startMarker, Args: ctx.Match.Args}
+		name := blockName(marker.Args)
+		employees, ok := h.blocks[name]
+		if !ok {
+			return fmt.Errorf("employees: unknown block %q", name)
+		}
+
+		w := employeeWriter{
+			block:     name,
+			marker:    ctx.Match.Text,
+			employees: employees,
+			days:      ctx.Period.Days(),
+			fill:      r.days.WeekendFill,
+		}
+		w.groupBy, _ = marker.Arg(groupOption)
+		if w.fill != "" {
+			w.filled = nonWorkingDays(ctx.Period, ctx.Calendar)
+		}
+		if h.highlight != nil {
+			opts := h.highlight.opts
+			if opts.Period.Start.IsZero() {
+				opts.Period = ctx.Period
+			}
+			w.report = domain.ValidateEmployees(employees, opts)
+			w.highlightColor = h.highlight.color
+		}
+		return w.write(ctx)
 	})
 }
 
-func writeEmployees(f *excelize.File, sheet string, row, col int, employees []domain.Employee) error {
-	if err := f.RemoveRow(sheet, row+1); err != nil {
-		return fmt.Errorf("remove template row: %w", err)
+// blockName returns the block named by the marker arguments: the first
+// argument that is not an option such as group=Field.
+func blockName(args []string) string {
+	for _, arg := range args {
+		k, v, ok := strings.Cut(arg, "=")
+		switch {
+		case !ok:
+			return arg
+		case k != groupOption:
+			return v
+		}
 	}
+	return DefaultBlock
+}
 
-	if err := f.InsertRows(sheet, row+1, len(employees)); err != nil {
+// employeeWriter writes one employee block.
+type employeeWriter struct {
+	block     string // block name recorded for the formula pass
+	marker    string // the {{start_process …}} marker text
+	groupBy   string // employee field to group by; "" = no grouping
+	employees []domain.Employee
+	days      int    // attendance columns in the reporting period
+	fill      string // weekend fill color; "" = none
+	filled    []bool // days that get the weekend fill
+
+	report         *domain.Report // validation issues to highlight; nil = none
+	highlightColor string
+}
+
+func (w employeeWriter) write(ctx *Context) error {
+	f, sheet, row := ctx.File, ctx.Sheet, ctx.Row
+
+	layout, err := readEmployeeLayout(f, sheet, row, ctx.Col)
+	if err != nil {
+		return fmt.Errorf("read layout: %w", err)
+	}
+
+	groups := []employeeGroup{ungrouped(w.employees)}
+	var header, subtotal *rowTemplate
+	if w.groupBy != "" {
+		groups = groupEmployees(w.employees, w.groupBy)
+		if header, err = readRowTemplate(f, sheet, row-1, groupHeaderMarker); err != nil {
+			return fmt.Errorf("read group header: %w", err)
+		}
+		if subtotal, err = readRowTemplate(f, sheet, row+1, groupSubtotalMarker); err != nil {
+			return fmt.Errorf("read group subtotal: %w", err)
+		}
+	}
+
+	// Template rows: [header] employee [subtotal].
+	first, templateRows := row, 1
+	if header != nil {
+		first--
+		templateRows++
+	}
+	if subtotal != nil {
+		templateRows++
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += len(g.employees)
+		if header != nil {
+			total++
+		}
+		if subtotal != nil {
+			total++
+		}
+	}
+
+	if err := defineScope(f, sheet, w.block, first, first+templateRows); err != nil {
+		return err
+	}
+
+	if err := ctx.RemoveRows(first, templateRows); err != nil {
+		return fmt.Errorf("remove template rows: %w", err)
+	}
+
+	if err := ctx.InsertRows(first, total); err != nil {
 		return fmt.Errorf("insert rows: %w", err)
 	}
 
-	sm := NewStyleManager(f)
+	var written []group
+	next := first
+	for _, g := range groups {
+		render := func(tmpl string) string { return renderGroupCell(tmpl, g) }
 
-	for i, emp := range employees {
-		empRow := row + i
-		if err := writeEmployeeRow(f, sm, sheet, empRow, col, emp); err != nil {
-			return fmt.Errorf("employee %d: %w", emp.Id, err)
+		if header != nil {
+			if err := header.write(f, sheet, next, render); err != nil {
+				return fmt.Errorf("group %q header: %w", g.value, err)
+			}
+			next++
+		}
+
+		for i, emp := range g.employees {
+			if err := w.writeRow(f, ctx.Styles, sheet, next+i, layout, emp); err != nil {
+				return fmt.Errorf("employee %d: %w", emp.Id, err)
+			}
+			if w.report == nil {
+				continue
+			}
+			issues := w.report.ForEmployee(g.index[i])
+			if err := w.highlightIssues(f, ctx.Styles, sheet, next+i, layout, issues); err != nil {
+				return fmt.Errorf("employee %d: %w", emp.Id, err)
+			}
+		}
+		written = append(written, group{firstRow: next, lastRow: next + len(g.employees) - 1, subtotal: subtotal != nil})
+		next += len(g.employees)
+
+		if subtotal != nil {
+			if err := subtotal.write(f, sheet, next, render); err != nil {
+				return fmt.Errorf("group %q subtotal: %w", g.value, err)
+			}
+			next++
 		}
 	}
 
-	return nil
+	b := block{
+		name:     w.block,
+		firstRow: first,
+		lastRow:  first + total - 1,
+		attStart: layout.attStart,
+		attEnd:   layout.attStart + w.days - 1,
+	}
+	if err := defineBlock(f, sheet, b); err != nil {
+		return err
+	}
+	if w.groupBy == "" {
+		return nil
+	}
+	return defineGroups(f, sheet, b, written)
 }
 
-func writeEmployeeRow(f *excelize.File, sm *StyleManager, sheet string, row, col int, emp domain.Employee) error {
-	for c, def := range columns {
-		cell := excel.CellName(row, col+c)
-		if err := f.SetCellStr(sheet, cell, def.value(emp)); err != nil {
-			return fmt.Errorf("col %d: %w", c, err)
+// writeRow writes one employee row. Attendance cells of days marked in
+// w.filled get the weekend fill on top of the attendance style. Entries past
+// the reporting period are dropped rather than spilling into the columns
+// after the attendance range.
+func (w employeeWriter) writeRow(f *excelize.File, sm *StyleManager, sheet string, row int, layout employeeLayout, emp domain.Employee) error {
+	centeredStyle, err := sm.Centered()
+	if err != nil {
+		return fmt.Errorf("centered style: %w", err)
+	}
+
+	for _, field := range layout.fields {
+		cell := excel.CellName(row, field.col)
+		if err := f.SetCellStr(sheet, cell, renderEmployeeCell(field.tmpl, w.marker, emp)); err != nil {
+			return fmt.Errorf("col %d: %w", field.col, err)
 		}
 
-		styleID, err := def.style(sm)
-		if err != nil {
-			return fmt.Errorf("style col %d: %w", c, err)
+		styleID := field.style
+		if styleID == 0 {
+			styleID = centeredStyle
 		}
 		if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
-			return fmt.Errorf("set style col %d: %w", c, err)
+			return fmt.Errorf("set style col %d: %w", field.col, err)
 		}
 	}
 
-	attStart := col + len(columns)
-	centeredStyle, err := sm.Centered()
+	attStyle := layout.attStyle
+	if attStyle == 0 {
+		attStyle = centeredStyle
+	}
+	weekendStyle, err := fillStyle(sm, attStyle, w.fill)
 	if err != nil {
-		return fmt.Errorf("attendance style: %w", err)
+		return fmt.Errorf("attendance fill style: %w", err)
 	}
 
-	for i, att := range emp.Attendance {
-		cell := excel.CellName(row, attStart+i)
+	for i, att := range emp.Attendance[:min(len(emp.Attendance), w.days)] {
+		cell := excel.CellName(row, layout.attStart+i)
 		if err := f.SetCellStr(sheet, cell, att); err != nil {
 			return fmt.Errorf("attendance %d: %w", i, err)
 		}
-		if err := f.SetCellStyle(sheet, cell, cell, centeredStyle); err != nil {
+		style := attStyle
+		if i < len(w.filled) && w.filled[i] {
+			style = weekendStyle
+		}
+		if err := f.SetCellStyle(sheet, cell, cell, style); err != nil {
 			return fmt.Errorf("attendance style %d: %w", i, err)
 		}
 	}
@@ -115,142 +452,400 @@ func writeEmployeeRow(f *excelize.File, sm *StyleManager, sheet string, row, col
 // FormulaKey pairs a template placeholder with an optional formula generator.
 //
 // Key is the placeholder in the Excel template (e.g. "{{d}}").
-// FormulaFn receives the attendance cell range and returns the formula string.
-// Set FormulaFn to nil for a style-only key (e.g. "{{}}") that applies
+// Formula receives the attendance range of one employee and builds the
+// formula with the formula package; the result is validated when rendered.
+// FormulaFn is the raw alternative: it receives the range as text (e.g.
+// "E5:AF5") and returns the formula string. Formula wins when both are set.
+// Leave both nil for a style-only key (e.g. "{{}}") that applies
 // the centered style to each employee cell without writing a formula.
+// ValueFn optionally computes the same result in Go from the attendance
+// values of one employee; it is used when the registry evaluation is
+// CachedValues or ValuesOnly (see CountIFValue, SumNumValue, CountNumValue).
 type FormulaKey struct {
 	Key       string
+	Formula   func(attRange formula.Expr) formula.Expr
 	FormulaFn func(attRange string) string
+	ValueFn   func(attendance []string) float64
+}
+
+// render returns the formula of k over attRange, or "" for a style-only key.
+func (k FormulaKey) render(attRange string) (string, error) {
+	switch {
+	case k.Formula != nil:
+		text, err := formula.Render(k.Formula(formula.ParseRange(attRange)))
+		if err != nil {
+			return "", fmt.Errorf("key %s: %w", k.Key, err)
+		}
+		return text, nil
+	case k.FormulaFn != nil:
+		text := k.FormulaFn(attRange)
+		if msg, ok := strings.CutPrefix(text, formulaFnError); ok {
+			return "", fmt.Errorf("key %s: %s", k.Key, msg)
+		}
+		return text, nil
+	}
+	return "", nil
 }
 
-// CountIFFormula returns a FormulaFn that counts occurrences of symbol across
-// an attendance range, multiplied by value.
+// CountIFExpr returns a Formula that counts occurrences of symbol across an
+// attendance range, multiplied by value.
 //
 //	symbol "W", value 1 → SUMPRODUCT((range="W")*1)  — counts each "W"
-func CountIFFormula(symbol string, value int) func(string) string {
-	return func(cellRange string) string {
-		return fmt.Sprintf(`SUMPRODUCT((%s="%s")*%d)`, cellRange, symbol, value)
+func CountIFExpr(symbol string, value int) func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		return formula.SumProduct(formula.Mul(formula.Eq(attRange, formula.Str(symbol)), formula.Int(value)))
 	}
 }
 
-// SumNumFormula returns a FormulaFn that sums all numeric values in the
+// SumNumExpr returns a Formula that sums all numeric values in the
 // attendance range, ignoring non-numeric cells.
 //
 //	"8", "W", "8" → 8 + 0 + 8 = 16
-func SumNumFormula() func(string) string {
-	return func(cellRange string) string {
-		return fmt.Sprintf(`IFERROR(SUMPRODUCT(IFERROR(VALUE(%s),0)),0)`, cellRange)
+func SumNumExpr() func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		return formula.IfError(formula.SumProduct(formula.IfError(formula.Value(attRange), formula.Int(0))), formula.Int(0))
 	}
 }
 
-// CountNumFormula returns a FormulaFn that counts how many cells in the
+// CountNumExpr returns a Formula that counts how many cells in the
 // attendance range contain a number, ignoring non-numeric cells.
 //
 //	"8", "W", "8" → 1 + 0 + 1 = 2
+func CountNumExpr() func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		isNum := formula.Add(formula.Mul(formula.Value(attRange), formula.Int(0)), formula.Int(1))
+		return formula.IfError(formula.SumProduct(formula.IfError(isNum, formula.Int(0))), formula.Int(0))
+	}
+}
+
+// CountIFFormula is the FormulaFn form of CountIFExpr. A symbol that cannot
+// be written as an Excel string literal (longer than 255 characters) is
+// reported as an error of the key when the formula is rendered.
+func CountIFFormula(symbol string, value int) func(string) string {
+	return formulaFn(CountIFExpr(symbol, value))
+}
+
+// CheckedCountIFFormula is CountIFFormula with the symbol checked up front.
+func CheckedCountIFFormula(symbol string, value int) (func(string) string, error) {
+	if _, err := formula.Render(formula.Str(symbol)); err != nil {
+		return nil, fmt.Errorf("count %q: %w", symbol, err)
+	}
+	return CountIFFormula(symbol, value), nil
+}
+
+// SumNumFormula is the FormulaFn form of SumNumExpr.
+func SumNumFormula() func(string) string {
+	return formulaFn(SumNumExpr())
+}
+
+// CountNumFormula is the FormulaFn form of CountNumExpr.
 func CountNumFormula() func(string) string {
-	return func(cellRange string) string {
-		return fmt.Sprintf(`IFERROR(SUMPRODUCT(IFERROR(VALUE(%s)*0+1,0)),0)`, cellRange)
+	return formulaFn(CountNumExpr())
+}
+
+// formulaFnError prefixes the text a formulaFn adapter returns in place of a
+// formula that does not render; FormulaKey.render reports the rest as the
+// key's error.
+const formulaFnError = "\x00"
+
+// formulaFn adapts a Formula builder to the FormulaFn signature.
+func formulaFn(build func(formula.Expr) formula.Expr) func(string) string {
+	return func(attRange string) string {
+		text, err := formula.Render(build(formula.ParseRange(attRange)))
+		if err != nil {
+			return formulaFnError + err.Error()
+		}
+		return text
 	}
 }
 
 // combFormulaHandler is shared across all formula key registrations.
-// When any registered key is found in a cell, it combines the formulas
-// of ALL keys present in that cell and writes one formula per employee row.
+// When any registered key is found in a cell, it handles every formula cell of
+// that template row: it combines the formulas of ALL keys present in each cell
+// and writes one formula per employee row, then removes the template row.
 type combFormulaHandler struct {
 	employeeCount int
 	attStart      int // 0-based column where attendance data begins
 	keys          []FormulaKey
-	sm            *StyleManager        // lazily initialized on first handle call
-	removedRows   map[string]struct{}  // tracks formula rows already removed
 }
 
-func (h *combFormulaHandler) handle(f *excelize.File, sheet string, row, col int, value string) error {
-	if h.sm == nil {
-		h.sm = NewStyleManager(f)
+func (h *combFormulaHandler) handle(ctx *Context) error {
+	f, sheet, row := ctx.File, ctx.Sheet, ctx.Row
+
+	centeredStyle, err := ctx.Styles.Centered()
+	if err != nil {
+		return fmt.Errorf("formula cell style: %w", err)
 	}
-	if h.removedRows == nil {
-		h.removedRows = make(map[string]struct{})
+
+	attStart, attEnd := h.attStart, h.attStart+ctx.Period.Days()-1
+	spans := []group{{firstRow: row - h.employeeCount, lastRow: row - 1}}
+	rec := formulaRecord{
+		firstRow:    row - h.employeeCount,
+		lastRow:     row - 1,
+		cols:        make(map[string]int),
+		formulaOnly: make(map[int]bool),
+		edits:       len(ctx.edits()),
+	}
+	if attStart == DetectAttendance {
+		b, ok := blockOf(f, sheet, row)
+		if !ok {
+			return fmt.Errorf("detect attendance: no employee block above row %d on sheet %q", row+1, sheet)
+		}
+		attStart, attEnd = b.attStart, b.attEnd
+		spans = []group{{firstRow: b.firstRow, lastRow: b.lastRow}}
+		if groups := blockGroups(f, sheet, b); len(groups) > 0 {
+			spans = groups
+		}
+		rec.block, rec.firstRow, rec.lastRow = b.name, b.firstRow, b.lastRow
 	}
 
-	centeredStyle, err := h.sm.Centered()
+	// Multiple keys usually share the template row (e.g. {{t}}, {{d}}, {{w}}),
+	// so the whole row is handled on the first hit before it is removed.
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
 	if err != nil {
-		return fmt.Errorf("formula cell style: %w", err)
+		return fmt.Errorf("get rows: %w", err)
+	}
+	var cells []string
+	if row < len(rows) {
+		cells = rows[row]
+	}
+
+	w := spanWriter{
+		f: f, sheet: sheet, rows: rows, mode: ctx.Evaluation,
+		attStart: attStart, attEnd: attEnd, style: centeredStyle,
+	}
+	for col, value := range cells {
+		if value == "" {
+			continue
+		}
+		for _, k := range h.keys {
+			if strings.Contains(value, k.Key) {
+				rec.cols[k.Key] = col
+			}
+		}
+		for _, span := range spans {
+			computed, err := h.writeSpan(w, col, value, span)
+			if err != nil {
+				return err
+			}
+			if !computed {
+				rec.formulaOnly[col] = true
+			}
+		}
 	}
 
-	attEnd := h.attStart + currentMonthDays() - 1
-	firstEmpRow := row - h.employeeCount
+	ctx.Store.Set(formulaRecordKey(sheet), rec)
 
-	for empRow := firstEmpRow; empRow < row; empRow++ {
-		attRange := excel.CellName(empRow, h.attStart) + ":" + excel.CellName(empRow, attEnd)
+	if err := ctx.RemoveRows(row, 1); err != nil {
+		return fmt.Errorf("remove formula row: %w", err)
+	}
+
+	return nil
+}
 
-		formula, matched := h.buildFormula(value, attRange)
+// spanWriter holds what writeSpan needs besides the template cell: the sheet,
+// its rows as read before any formula was written, and the attendance columns.
+type spanWriter struct {
+	f                *excelize.File
+	sheet            string
+	rows             [][]string
+	mode             Evaluation
+	attStart, attEnd int
+	style            int
+}
+
+// attendance returns the attendance values of row, padded to the full range.
+func (w spanWriter) attendance(row int) []string {
+	values := make([]string, w.attEnd-w.attStart+1)
+	if row >= 0 && row < len(w.rows) {
+		for i := range values {
+			if c := w.attStart + i; c < len(w.rows[row]) {
+				values[i] = w.rows[row][c]
+			}
+		}
+	}
+	return values
+}
+
+// writeSpan writes the formulas of one template cell into the employee rows
+// of span and, if span has a subtotal row, a SUBTOTAL over those rows.
+// computed is false when a formula was written without its value.
+func (h *combFormulaHandler) writeSpan(w spanWriter, col int, value string, span group) (computed bool, err error) {
+	f, sheet := w.f, w.sheet
+	written := false
+	computed = w.mode != FormulasOnly
+	var sum float64
+	for empRow := span.firstRow; empRow <= span.lastRow; empRow++ {
+		attRange := excel.CellName(empRow, w.attStart) + ":" + excel.CellName(empRow, w.attEnd)
+
+		res, matched, err := h.buildFormula(value, attRange, w.attendance(empRow))
+		if err != nil {
+			return false, err
+		}
 		if !matched {
-			continue
+			return true, nil
 		}
 
 		cell := excel.CellName(empRow, col)
-		if formula != "" {
-			if err := f.SetCellFormula(sheet, cell, formula); err != nil {
-				return fmt.Errorf("set formula at %s: %w", cell, err)
+		if res.formula != "" {
+			if w.mode == ValuesOnly && !res.computed {
+				return false, fmt.Errorf("formula handler: %s at %s: a key has no ValueFn", value, cell)
+			}
+			if err := setResult(f, sheet, cell, res.formula, res.value, res.computed, w.mode); err != nil {
+				return false, fmt.Errorf("set formula at %s: %w", cell, err)
 			}
+			written = true
+			computed = computed && res.computed
+			sum += res.value
 		}
-		if err := f.SetCellStyle(sheet, cell, cell, centeredStyle); err != nil {
-			return fmt.Errorf("set style at %s: %w", cell, err)
+		if err := f.SetCellStyle(sheet, cell, cell, w.style); err != nil {
+			return false, fmt.Errorf("set style at %s: %w", cell, err)
 		}
 	}
 
-	// Remove the template formula row exactly once — multiple keys can live in
-	// the same row (e.g. {{t}}, {{d}}, {{w}}), so the handler is called once per
-	// cell. Tracking ensures RemoveRow is called only on the first hit.
-	key := fmt.Sprintf("%s:%d", sheet, row)
-	if _, done := h.removedRows[key]; !done {
-		h.removedRows[key] = struct{}{}
-		if err := f.RemoveRow(sheet, row+1); err != nil {
-			return fmt.Errorf("remove formula row: %w", err)
-		}
+	if !span.subtotal || !written {
+		return computed, nil
+	}
+	colRange := excel.CellName(span.firstRow, col) + ":" + excel.CellName(span.lastRow, col)
+	cell := excel.CellName(span.lastRow+1, col)
+	if err := setResult(f, sheet, cell, blankZero("SUBTOTAL(9,"+colRange+")"), sum, computed, w.mode); err != nil {
+		return false, fmt.Errorf("set subtotal at %s: %w", cell, err)
 	}
+	return computed, nil
+}
 
-	return nil
+// blankZero wraps expr so that a zero result shows as an empty cell.
+func blankZero(expr string) string {
+	return fmt.Sprintf(`IF(%s=0,"",(%s))`, expr, expr)
 }
 
-// buildFormula collects formulas from all keys found in value, returning the
-// combined wrapped formula and whether any key matched. If all matching keys
-// have nil FormulaFn (style-only), formula is empty but matched is true.
-func (h *combFormulaHandler) buildFormula(value, attRange string) (formula string, matched bool) {
-	var parts []string
+// formulaResult is the combined formula of one template cell for one employee
+// and its value computed in Go. computed is false when a matching key has no
+// ValueFn.
+type formulaResult struct {
+	formula  string
+	value    float64
+	computed bool
+}
+
+// buildFormula collects formulas from all keys and {{count …}} placeholders
+// found in value, returning the combined wrapped formula, its value over
+// attendance, and whether any key matched. If all matching keys have nil
+// FormulaFn (style-only), the formula is empty but matched is true.
+func (h *combFormulaHandler) buildFormula(value, attRange string, attendance []string) (res formulaResult, matched bool, err error) {
+	keys := make([]FormulaKey, 0, len(h.keys))
 	for _, k := range h.keys {
-		if !strings.Contains(value, k.Key) {
+		if strings.Contains(value, k.Key) {
+			keys = append(keys, k)
+		}
+	}
+	for _, p := range ParsePlaceholders(value) {
+		if p.Name != countPlaceholder {
+			continue
+		}
+		k, err := countKey(p)
+		if err != nil {
+			return formulaResult{}, false, err
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return formulaResult{}, false, nil
+	}
+
+	var parts []string
+	res.computed = true
+	for _, k := range keys {
+		part, err := k.render(attRange)
+		if err != nil {
+			return formulaResult{}, false, fmt.Errorf("formula handler: %w", err)
+		}
+		if part == "" {
 			continue
 		}
-		matched = true
-		if k.FormulaFn != nil {
-			parts = append(parts, k.FormulaFn(attRange))
+		parts = append(parts, part)
+		if k.ValueFn == nil {
+			res.computed = false
+			continue
 		}
+		res.value += k.ValueFn(attendance)
 	}
 	if len(parts) == 0 {
-		return "", matched
+		return formulaResult{}, true, nil
 	}
-	combined := strings.Join(parts, "+")
-	return fmt.Sprintf(`IF(%s=0,"",(%s))`, combined, combined), true
+	res.formula = blankZero(strings.Join(parts, "+"))
+	return res, true, nil
 }
 
+// countPlaceholder is the name of the {{count "SYMBOL" [WEIGHT]}} placeholder
+// understood by every formula handler.
+const countPlaceholder = "count"
+
+// countKey turns {{count "SYMBOL" [WEIGHT]}} into a FormulaKey built from
+// CountIFExpr and CountIFValue. WEIGHT defaults to 1.
+func countKey(p Placeholder) (FormulaKey, error) {
+	if len(p.Args) < 1 || len(p.Args) > 2 {
+		return FormulaKey{}, fmt.Errorf("formula handler: %s: want a symbol and an optional weight", p.Text)
+	}
+	weight := 1
+	if len(p.Args) == 2 {
+		w, err := strconv.Atoi(p.Args[1])
+		if err != nil {
+			return FormulaKey{}, fmt.Errorf("formula handler: %s: invalid weight %q", p.Text, p.Args[1])
+		}
+		weight = w
+	}
+	return FormulaKey{
+		Key:     p.Text,
+		Formula: CountIFExpr(p.Args[0], weight),
+		ValueFn: CountIFValue(p.Args[0], weight),
+	}, nil
+}
+
+// DetectAttendance tells RegisterFormulaHandler to take the employee rows and
+// attendance columns from the block each formula row is bound to instead of a
+// fixed column and row count.
+const DetectAttendance = -1
+
 // RegisterFormulaHandler registers per-employee formula handlers for each key.
 //
 // When the processor finds a template cell containing any of the registered keys,
 // it writes the appropriate Excel formula into each of the employeeCount rows
-// directly above that cell (one formula per employee). A cell may contain
-// multiple keys (e.g. "{{d}}{{t}}") — the resulting formulas are combined with "+".
+// directly above that cell (one formula per employee), or — with
+// DetectAttendance — into every row of the employee block it is bound to. A
+// cell may contain multiple keys (e.g. "{{d}}{{t}}") — the resulting formulas
+// are combined with "+".
+// All formula cells of the template row are handled together and the row is
+// then removed, along with anything else it holds.
 //
-// attStart is the 0-based column index where employee attendance data begins
-// (use AttendanceStartCol to compute it).
+// Besides the literal keys, the handler understands {{count "SYMBOL" WEIGHT}}
+// placeholders, which count SYMBOL across the attendance range multiplied by
+// WEIGHT (default 1), e.g. {{count "W"}} or {{count "8" 8}}. They need no
+// FormulaKey and may be combined with keys in the same cell.
+//
+// Cells below the formula row may hold {{total}} or {{total:KEY}} to total a
+// formula column over the employee rows (see handleTotal).
+//
+// With Registry.SetEvaluation, results are also computed in Go through each
+// key's ValueFn and written as cached values, or instead of the formulas.
+//
+// attStart is the 0-based column index where employee attendance data begins.
+// Pass DetectAttendance to take the rows and attendance columns from the
+// employee block the formula row is bound to — the block whose
+// {{start_process}} marker is the nearest above it in the template — as
+// recorded by RegisterEmployeeHandler or EmployeeHandler; employeeCount is
+// then ignored.
+// This is required for templates that declare their own columns or hold
+// several blocks per sheet. For a single block with the default columns,
+// AttendanceStartCol gives the fixed column.
 //
 // Example:
 //
 //	template.RegisterFormulaHandler(registry, 25, template.AttendanceStartCol(0), []template.FormulaKey{
-//	    {Key: "{{d}}", FormulaFn: template.CountIFFormula("8", 8)},
-//	    {Key: "{{w}}", FormulaFn: template.CountIFFormula("W", 1)},
-//	    {Key: "{{t}}", FormulaFn: func(r string) string {
-//	        return fmt.Sprintf(`SUMPRODUCT(IFERROR(VALUE(%s),(%s<>"")*1))`, r, r)
+//	    {Key: "{{d}}", Formula: template.CountIFExpr("8", 8)},
+//	    {Key: "{{w}}", Formula: template.CountIFExpr("W", 1), ValueFn: template.CountIFValue("W", 1)},
+//	    {Key: "{{t}}", Formula: func(r formula.Expr) formula.Expr {
+//	        return formula.SumProduct(formula.IfError(formula.Value(r), formula.Mul(formula.Ne(r, formula.Str("")), formula.Int(1))))
 //	    }},
 //	})
 func RegisterFormulaHandler(r *Registry, employeeCount, attStart int, keys []FormulaKey) {
@@ -260,15 +855,38 @@ func RegisterFormulaHandler(r *Registry, employeeCount, attStart int, keys []For
 		keys:          keys,
 	}
 	for _, k := range keys {
-		r.Register(k.Key, h.handle)
+		r.register("formula", k.Key, h.handle)
 	}
+	r.registerPlaceholder("formula", countPlaceholder, h.handle)
+	r.registerRegexp("total", totalPat, handleTotal)
 }
 
 // ---------- {{days}} ----------
 
-func handleDays(f *excelize.File, sheet string, row, col int, _ string) error {
-	days := currentMonthDays()
-	if err := f.InsertCols(sheet, excel.IndexToColumn(col+1), days-1); err != nil {
+// nonWorkingFontColor is the font color of day numbers that fall on a rest
+// day or public holiday when the registry has a calendar.
+const nonWorkingFontColor = "C00000"
+
+// DaysOptions configures the {{days}} header.
+//
+// Weekdays writes localized weekday abbreviations (Du, Si, Ça…) into the row
+// directly below the day numbers; the template must reserve that row.
+// Locale selects the abbreviation language ("tk", "ru" or "en"; default "tk").
+// WeekendFill is a hex fill color (e.g. "FFE699") applied to the header and
+// attendance cells of rest days and holidays. Without a registry calendar,
+// Saturday and Sunday are treated as rest days.
+type DaysOptions struct {
+	Weekdays    bool
+	Locale      string
+	WeekendFill string
+}
+
+func handleDays(ctx *Context, opts DaysOptions) error {
+	f, sheet, row, col := ctx.File, ctx.Sheet, ctx.Row, ctx.Col
+	period, cal := ctx.Period, ctx.Calendar
+
+	days := period.Days()
+	if err := ctx.InsertCols(col+1, days-1); err != nil {
 		return fmt.Errorf("insert cols: %w", err)
 	}
 
@@ -291,7 +909,7 @@ func handleDays(f *excelize.File, sheet string, row, col int, _ string) error {
 
 	for i := range days {
 		cell := excel.CellName(row, col+i)
-		if err := f.SetCellInt(sheet, cell, int64(i+1)); err != nil {
+		if err := f.SetCellInt(sheet, cell, int64(period.Day(i).Day())); err != nil {
 			return fmt.Errorf("set day %d: %w", i+1, err)
 		}
 	}
@@ -305,6 +923,30 @@ func handleDays(f *excelize.File, sheet string, row, col int, _ string) error {
 		return fmt.Errorf("set style: %w", err)
 	}
 
+	sm := ctx.Styles
+
+	if cal != nil {
+		if err := tagNonWorkingDays(f, sm, sheet, row, col, styleID, cal.Kinds(period)); err != nil {
+			return err
+		}
+	}
+
+	if opts.Weekdays {
+		if err := writeWeekdays(f, sheet, row+1, col, period, opts.Locale, styleID); err != nil {
+			return err
+		}
+	}
+
+	if opts.WeekendFill != "" {
+		headerRows := []int{row}
+		if opts.Weekdays {
+			headerRows = append(headerRows, row+1)
+		}
+		if err := fillNonWorkingDays(f, sm, sheet, headerRows, col, nonWorkingDays(period, cal), opts.WeekendFill); err != nil {
+			return err
+		}
+	}
+
 	colStart := excel.IndexToColumn(col)
 	colEnd := excel.IndexToColumn(col + days - 1)
 	if err := f.SetColWidth(sheet, colStart, colEnd, 4); err != nil {
@@ -314,6 +956,102 @@ func handleDays(f *excelize.File, sheet string, row, col int, _ string) error {
 	return nil
 }
 
+// tagNonWorkingDays recolors the day numbers of rest days and holidays,
+// keeping the rest of the header style.
+func tagNonWorkingDays(f *excelize.File, sm *StyleManager, sheet string, row, col, styleID int, kinds []calendar.Kind) error {
+	tagged, err := sm.Derived(styleID, "non_working", func(s *excelize.Style) {
+		if s.Font == nil {
+			s.Font = &excelize.Font{}
+		}
+		s.Font.Color = nonWorkingFontColor
+	})
+	if err != nil {
+		return fmt.Errorf("non-working day style: %w", err)
+	}
+
+	for i, kind := range kinds {
+		if kind == calendar.Workday {
+			continue
+		}
+		cell := excel.CellName(row, col+i)
+		if err := f.SetCellStyle(sheet, cell, cell, tagged); err != nil {
+			return fmt.Errorf("tag day %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// writeWeekdays writes the weekday abbreviation of every day into row.
+// The row keeps its own style when its first cell has one; otherwise it
+// takes the day-number style.
+func writeWeekdays(f *excelize.File, sheet string, row, col int, period domain.Period, locale string, dayStyleID int) error {
+	styleID, _ := f.GetCellStyle(sheet, excel.CellName(row, col))
+	if styleID == 0 {
+		styleID = dayStyleID
+	}
+
+	for i := range period.Days() {
+		cell := excel.CellName(row, col+i)
+		if err := f.SetCellStr(sheet, cell, calendar.WeekdayAbbr(locale, period.Day(i).Weekday())); err != nil {
+			return fmt.Errorf("set weekday %d: %w", i+1, err)
+		}
+		if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+			return fmt.Errorf("weekday style %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// fillNonWorkingDays adds the fill color to the cells of the filled day
+// columns in each of rows, keeping each cell's existing style otherwise.
+func fillNonWorkingDays(f *excelize.File, sm *StyleManager, sheet string, rows []int, col int, filled []bool, color string) error {
+	for _, row := range rows {
+		for i, ok := range filled {
+			if !ok {
+				continue
+			}
+			cell := excel.CellName(row, col+i)
+			baseID, _ := f.GetCellStyle(sheet, cell)
+			styleID, err := fillStyle(sm, baseID, color)
+			if err != nil {
+				return fmt.Errorf("fill style day %d: %w", i+1, err)
+			}
+			if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+				return fmt.Errorf("fill day %d: %w", i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fillStyle returns baseID with a solid fill of color. An empty color
+// returns baseID unchanged.
+func fillStyle(sm *StyleManager, baseID int, color string) (int, error) {
+	if color == "" {
+		return baseID, nil
+	}
+	return sm.Derived(baseID, "fill:"+color, func(s *excelize.Style) {
+		s.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{color}}
+	})
+}
+
+// nonWorkingDays reports, for every day of period, whether it is a rest day
+// or holiday. Without a calendar Saturday and Sunday are rest days.
+func nonWorkingDays(period domain.Period, cal *calendar.Calendar) []bool {
+	if cal == nil {
+		cal = calendar.New()
+	}
+
+	filled := make([]bool, period.Days())
+	for i, kind := range cal.Kinds(period) {
+		filled[i] = kind != calendar.Workday
+	}
+	return filled
+}
+
 // ---------- ReplaceHandler ----------
 
 // ReplaceHandler accumulates key→value pairs and registers a single shared
@@ -349,12 +1087,12 @@ func (h *ReplaceHandler) Add(key, val string) *ReplaceHandler {
 // causes all pairs to be replaced in the cell.
 func (h *ReplaceHandler) Register(r *Registry) {
 	for _, p := range h.pairs {
-		r.Register(p.key, h.apply)
+		r.register("replace", p.key, h.apply)
 	}
 }
 
-func (h *ReplaceHandler) apply(f *excelize.File, sheet string, row, col int, value string) error {
-	cell := excel.CellName(row, col)
+func (h *ReplaceHandler) apply(ctx *Context) error {
+	f, sheet, cell, value := ctx.File, ctx.Sheet, ctx.Cell(), ctx.Value
 
 	styleID, _ := f.GetCellStyle(sheet, cell)
 
@@ -384,8 +1122,8 @@ func RegisterReplaceHandler(r *Registry, key, val string) {
 
 // ---------- {{working_time}} ----------
 
-func handleWorkingTime(f *excelize.File, sheet string, row, col int, value string) error {
-	cell := excel.CellName(row, col)
+func handleWorkingTime(ctx *Context) error {
+	f, sheet, cell, value := ctx.File, ctx.Sheet, ctx.Cell(), ctx.Value
 
 	styleID, _ := f.GetCellStyle(sheet, cell)
 	replaced := strings.ReplaceAll(value, "{{working_time}}", domain.KeyMap["{{working_time}}"])
@@ -426,8 +1164,8 @@ func handleWorkingTime(f *excelize.File, sheet string, row, col int, value strin
 //	    {Name: "Kanuna laýyk işe gelmezlik",      Key: "C"},
 //	})
 func RegisterMarksHandler(r *Registry, marks []domain.Mark) {
-	r.Register("{{marks_list}}", func(f *excelize.File, sheet string, row, col int, _ string) error {
-		return writeMarks(f, sheet, row, col, marks)
+	r.register("marks", "{{marks_list}}", func(ctx *Context) error {
+		return writeMarks(ctx, marks)
 	})
 }
 
@@ -444,7 +1182,9 @@ func numericKey(key string) bool {
 	return true
 }
 
-func writeMarks(f *excelize.File, sheet string, row, col int, marks []domain.Mark) error {
+func writeMarks(ctx *Context, marks []domain.Mark) error {
+	f, sheet, row, col := ctx.File, ctx.Sheet, ctx.Row, ctx.Col
+
 	// Skip marks whose Key is a plain number (e.g. "8" for worked hours).
 	filtered := marks[:0:0]
 	for _, m := range marks {
@@ -473,7 +1213,7 @@ func writeMarks(f *excelize.File, sheet string, row, col int, marks []domain.Mar
 		}
 	}
 
-	if err := f.RemoveRow(sheet, row+1); err != nil {
+	if err := ctx.RemoveRows(row, 1); err != nil {
 		return fmt.Errorf("marks: remove template row: %w", err)
 	}
 
@@ -485,7 +1225,7 @@ func writeMarks(f *excelize.File, sheet string, row, col int, marks []domain.Mar
 		return fmt.Errorf("marks: clean phantom rows: %w", err)
 	}
 
-	if err := f.InsertRows(sheet, row+1, len(marks)); err != nil {
+	if err := ctx.InsertRows(row, len(marks)); err != nil {
 		return fmt.Errorf("marks: insert rows: %w", err)
 	}
 
@@ -542,24 +1282,35 @@ var mergeCodePat = regexp.MustCompile(`\[(\d+):(\d+)\]`)
 //	[0:2] → merge 2 cols to the right (horizontal only)
 //	[0:0] → strip code only, no merge
 //
+// The placeholder form {{merge extraRows extraCols}} (e.g. {{merge 1 2}}) is
+// equivalent.
+//
 // Run this in a separate pass (after all row/col insertions are done) so the
 // row indices are stable.
 func RegisterMergeHandler(r *Registry) {
-	r.Register("[", handleMergeCode)
+	r.registerRegexp("merge", mergeCodePat, handleMergeCode)
+	r.registerPlaceholder("merge", "merge", handleMergeCode)
 }
 
-func handleMergeCode(f *excelize.File, sheet string, row, col int, value string) error {
-	m := mergeCodePat.FindStringSubmatch(value)
-	if m == nil {
-		return nil // "[" present but not a merge code — skip
+func handleMergeCode(ctx *Context) error {
+	f, sheet, row, col, m := ctx.File, ctx.Sheet, ctx.Row, ctx.Col, ctx.Match
+
+	if len(m.Args) != 2 {
+		return fmt.Errorf("merge handler: %s: want 2 arguments (rows, cols), got %d", m.Text, len(m.Args))
 	}
 
-	extraRows, _ := strconv.Atoi(m[1])
-	extraCols, _ := strconv.Atoi(m[2])
+	extraRows, err := strconv.Atoi(m.Args[0])
+	if err != nil || extraRows < 0 {
+		return fmt.Errorf("merge handler: %s: invalid row count %q", m.Text, m.Args[0])
+	}
+	extraCols, err := strconv.Atoi(m.Args[1])
+	if err != nil || extraCols < 0 {
+		return fmt.Errorf("merge handler: %s: invalid col count %q", m.Text, m.Args[1])
+	}
 
-	cleaned := mergeCodePat.ReplaceAllString(value, "")
+	cleaned := stripMergeCodes(ctx.Value)
 
-	cell := excel.CellName(row, col)
+	cell := ctx.Cell()
 	styleID, _ := f.GetCellStyle(sheet, cell)
 
 	if err := f.SetCellStr(sheet, cell, cleaned); err != nil {
@@ -584,6 +1335,18 @@ func handleMergeCode(f *excelize.File, sheet string, row, col int, value string)
 	return nil
 }
 
+// stripMergeCodes removes every [r:c] code and {{merge …}} placeholder from
+// value. Only the first one is applied; the rest must not be left behind.
+func stripMergeCodes(value string) string {
+	value = mergeCodePat.ReplaceAllString(value, "")
+	for _, p := range ParsePlaceholders(value) {
+		if p.Name == "merge" {
+			value = strings.Replace(value, p.Text, "", 1)
+		}
+	}
+	return value
+}
+
 // ---------- RegisterBorderHandler ----------
 
 // RegisterBorderHandler registers a handler for the &1 marker.
@@ -598,31 +1361,30 @@ func handleMergeCode(f *excelize.File, sheet string, row, col int, value string)
 //
 // A single &1 registration supports multiple independent ranges per sheet:
 // after each pair is consumed the handler resets and waits for the next pair.
+// The pending corner is kept per sheet in the run store.
 func RegisterBorderHandler(r *Registry) {
-	h := &borderHandler{}
-	r.Register("&1", h.handle)
-}
-
-type borderHandler struct {
-	topLeft *[2]int // nil until first &1 is found
+	r.register("border", "&1", handleBorder)
 }
 
-func (h *borderHandler) handle(f *excelize.File, sheet string, row, col int, value string) error {
-	cell := excel.CellName(row, col)
+func handleBorder(ctx *Context) error {
+	f, sheet, row, col, cell := ctx.File, ctx.Sheet, ctx.Row, ctx.Col, ctx.Cell()
 
-	cleaned := strings.ReplaceAll(value, "&1", "")
+	cleaned := strings.ReplaceAll(ctx.Value, "&1", "")
 	if err := f.SetCellStr(sheet, cell, cleaned); err != nil {
 		return fmt.Errorf("border handler: strip marker at %s: %w", cell, err)
 	}
 
-	if h.topLeft == nil {
-		h.topLeft = &[2]int{row, col}
+	key := "border:topLeft:" + sheet
+	v, ok := ctx.Store.Get(key)
+	if !ok {
+		ctx.Store.Set(key, [2]int{row, col})
 		return nil
 	}
+	ctx.Store.Delete(key) // reset for the next pair
 
-	r1, c1 := h.topLeft[0], h.topLeft[1]
+	topLeft := v.([2]int)
+	r1, c1 := topLeft[0], topLeft[1]
 	r2, c2 := row, col
-	h.topLeft = nil // reset for the next pair
 
 	if r1 > r2 {
 		r1, r2 = r2, r1
@@ -687,10 +1449,3 @@ func removePhantomRows(f *excelize.File, sheet string, n int) error {
 	}
 	return nil
 }
-
-func currentMonthDays() int {
-	now := time.Now().Local()
-	year, month, _ := now.Date()
-	first := time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location())
-	return first.AddDate(0, 0, -1).Day()
-}
@@ -0,0 +1,77 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountIFFormula(t *testing.T) {
+	fn := CountIFFormula(`"W"`, 8)
+	if got, want := fn("E5:AF5"), `SUMPRODUCT((E5:AF5="""W""")*8)`; got != want {
+		t.Errorf("formula = %s, want %s", got, want)
+	}
+
+	long := strings.Repeat("x", 256)
+	key := FormulaKey{Key: "{{x}}", FormulaFn: CountIFFormula(long, 1)}
+	if _, err := key.render("E5:AF5"); err == nil || !strings.HasPrefix(err.Error(), "key {{x}}: ") {
+		t.Errorf("render with a 256-character symbol: error = %v, want a key {{x}} error", err)
+	}
+
+	if _, err := CheckedCountIFFormula(long, 1); err == nil {
+		t.Error("CheckedCountIFFormula with a 256-character symbol: want an error")
+	}
+	if fn, err := CheckedCountIFFormula("W", 1); err != nil || fn("A1:B1") != `SUMPRODUCT((A1:B1="W")*1)` {
+		t.Errorf("CheckedCountIFFormula(W) = %v", err)
+	}
+}
+
+func TestStripMergeCodes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Jemi[1:2]", "Jemi"},
+		{"[1:0]Ady[0:2]", "Ady"},
+		{"{{merge 1 2}}Ady", "Ady"},
+		{"Ady {{merge 1 2}}{{merge 0 1}}", "Ady "},
+		{"[1:1]{{merge  2 0 }}Ady", "Ady"},
+		{"{{merge 1 x}}[0:1]", ""},
+		{"[a:b] {{t}} [1]", "[a:b] {{t}} [1]"},
+	}
+
+	for _, tt := range tests {
+		if got := stripMergeCodes(tt.value); got != tt.want {
+			t.Errorf("stripMergeCodes(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLookupHandlerNames(t *testing.T) {
+	r := New()
+	RegisterDefaults(r)
+	RegisterMergeHandler(r)
+	RegisterBorderHandler(r)
+	r.Register("{{custom}}", func(*Context) error { return nil })
+
+	tests := []struct {
+		value   string
+		handler string
+	}{
+		{"{{days}}", "days"},
+		{"Ady[1:2]", "merge"},
+		{"{{merge 1 2}}", "merge"},
+		{"&1", "border"},
+		{"{{custom}}", ""},
+	}
+
+	for _, tt := range tests {
+		b, ok := r.Lookup(tt.value)
+		if !ok {
+			t.Errorf("Lookup(%q): no handler", tt.value)
+			continue
+		}
+		if b.Handler != tt.handler {
+			t.Errorf("Lookup(%q).Handler = %q, want %q", tt.value, b.Handler, tt.handler)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Validation highlighting ----------
+
+// highlight is the validation set up by EmployeeHandler.Highlight.
+type highlight struct {
+	opts  domain.ValidateOptions
+	color string
+}
+
+// highlightIssues fills the cells of the employee row that issues point at.
+func (w employeeWriter) highlightIssues(f *excelize.File, sm *StyleManager, sheet string, row int, layout employeeLayout, issues []domain.Issue) error {
+	for _, issue := range issues {
+		var cols []int
+		switch {
+		case issue.Day > 0:
+			// Entries past the period are not written; there is no cell.
+			if issue.Day <= w.days {
+				cols = append(cols, layout.attStart+issue.Day-1)
+			}
+		case issue.Field == domain.FieldAttendance:
+			for d := range w.days {
+				cols = append(cols, layout.attStart+d)
+			}
+		default:
+			for _, field := range layout.fields {
+				if strings.Contains(field.tmpl, "{{."+issue.Field+"}}") {
+					cols = append(cols, field.col)
+				}
+			}
+		}
+
+		for _, col := range cols {
+			cell := excel.CellName(row, col)
+			base, err := f.GetCellStyle(sheet, cell)
+			if err != nil {
+				return fmt.Errorf("highlight %s: %w", cell, err)
+			}
+			styleID, err := fillStyle(sm, base, w.highlightColor)
+			if err != nil {
+				return fmt.Errorf("highlight %s: %w", cell, err)
+			}
+			if err := f.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+				return fmt.Errorf("highlight %s: %w", cell, err)
+			}
+		}
+	}
+	return nil
+}
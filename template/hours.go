@@ -0,0 +1,204 @@
+package template
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/orayew2002/rast-excel/formula"
+)
+
+// ---------- Hours per code ----------
+
+// Hour kinds summed by the hours formulas; see domain.Entry.
+type hourKind int
+
+const (
+	dayHours hourKind = iota
+	nightHours
+	overtimeHours
+	hourEntries // 1 per entry with day hours, for counting
+)
+
+// maxEntryLen bounds the text MID reads from an attendance cell.
+const maxEntryLen = 99
+
+// HoursExpr returns a Formula that sums the day hours of the attendance cells
+// with the given code: "Y 8" and "Y 4/2" give 12 for code "Y". Code "" sums
+// plain hour entries such as "8" and "8/2".
+//
+// Cells must hold the canonical text written by domain.Entry.String; other
+// cells count as zero.
+func HoursExpr(code string) func(formula.Expr) formula.Expr {
+	return hoursExpr(code, dayHours)
+}
+
+// NightHoursExpr is like HoursExpr for night hours: "8/2" gives 2.
+func NightHoursExpr(code string) func(formula.Expr) formula.Expr {
+	return hoursExpr(code, nightHours)
+}
+
+// OvertimeExpr is like HoursExpr for overtime hours: "8+1.5" gives 1.5.
+func OvertimeExpr(code string) func(formula.Expr) formula.Expr {
+	return hoursExpr(code, overtimeHours)
+}
+
+// HourEntriesExpr returns a Formula that counts the attendance cells of code
+// that hold day hours: "8", "8/2" and "W" give 2 for code "".
+func HourEntriesExpr(code string) func(formula.Expr) formula.Expr {
+	return hoursExpr(code, hourEntries)
+}
+
+func hoursExpr(code string, kind hourKind) func(formula.Expr) formula.Expr {
+	return func(attRange formula.Expr) formula.Expr {
+		// cond selects the cells of code; hours is the "H/N+O" part of them.
+		cond, hours := hoursPart(attRange, code)
+
+		var value formula.Expr
+		switch kind {
+		case dayHours, hourEntries:
+			// Text up to the first "/" or "+".
+			end := find(formula.Str("/"), formula.Concat(substitute(hours, "+", "/"), formula.Str("/")))
+			value = left(hours, formula.Sub(end, formula.Int(1)))
+		case nightHours:
+			// Text between "/" and "+". Without a "/" (or with the "+"
+			// first) the length is negative, MID fails and the cell is 0.
+			slash := find(formula.Str("/"), formula.Concat(hours, formula.Str("/")))
+			plus := find(formula.Str("+"), formula.Concat(hours, formula.Str("+")))
+			value = mid(hours, formula.Add(slash, formula.Int(1)), formula.Sub(plus, slash, formula.Int(1)))
+		case overtimeHours:
+			// Text after "+"; without one it is empty and the cell 0.
+			plus := find(formula.Str("+"), formula.Concat(hours, formula.Str("+")))
+			value = mid(hours, formula.Add(plus, formula.Int(1)), formula.Int(maxEntryLen))
+		}
+
+		if kind == hourEntries {
+			isNumber := formula.Call("ISNUMBER", formula.Value(value))
+			return formula.SumProduct(formula.Mul(cond, isNumber))
+		}
+		return formula.SumProduct(formula.Mul(cond, formula.IfError(formula.Value(value), formula.Int(0))))
+	}
+}
+
+// hoursPart returns the condition selecting the attendance cells of code and
+// the hours text of those cells. Plain hour entries start with a digit. Cells
+// with a "," are skipped: VALUE would read "4,5" as 45.
+func hoursPart(attRange formula.Expr, code string) (cond, hours formula.Expr) {
+	noComma := formula.Call("ISERROR", find(formula.Str(","), attRange))
+	if code == "" {
+		isNumber := formula.Call("ISNUMBER", formula.Value(left(attRange, formula.Int(1))))
+		return formula.Mul(isNumber, noComma), attRange
+	}
+	prefix := code + " "
+	n := len([]rune(prefix))
+	cond = formula.Mul(formula.Eq(left(attRange, formula.Int(n)), formula.Str(prefix)), noComma)
+	return cond, mid(attRange, formula.Int(n+1), formula.Int(maxEntryLen))
+}
+
+func left(text, n formula.Expr) formula.Expr {
+	return formula.Call("LEFT", text, n)
+}
+
+func mid(text, start, n formula.Expr) formula.Expr {
+	return formula.Call("MID", text, start, n)
+}
+
+func find(needle, text formula.Expr) formula.Expr {
+	return formula.Call("FIND", needle, text)
+}
+
+func substitute(text formula.Expr, old, replacement string) formula.Expr {
+	return formula.Call("SUBSTITUTE", text, formula.Str(old), formula.Str(replacement))
+}
+
+// HoursValue is the Go counterpart of HoursExpr.
+func HoursValue(code string) func([]string) float64 {
+	return hoursValue(code, dayHours)
+}
+
+// NightHoursValue is the Go counterpart of NightHoursExpr.
+func NightHoursValue(code string) func([]string) float64 {
+	return hoursValue(code, nightHours)
+}
+
+// OvertimeValue is the Go counterpart of OvertimeExpr.
+func OvertimeValue(code string) func([]string) float64 {
+	return hoursValue(code, overtimeHours)
+}
+
+// HourEntriesValue is the Go counterpart of HourEntriesExpr.
+func HourEntriesValue(code string) func([]string) float64 {
+	return hoursValue(code, hourEntries)
+}
+
+// hoursValue sums hours over the entries of code. It cuts the text the way
+// the formulas do, so cells that are not in the canonical "CODE H/N+O" form
+// give the same result in Go and in Excel.
+func hoursValue(code string, kind hourKind) func([]string) float64 {
+	return func(attendance []string) float64 {
+		var sum float64
+		for _, s := range attendance {
+			h, ok := entryHours(s, code)
+			if !ok {
+				continue
+			}
+			v, ok := excelNumber(hoursText(h, kind))
+			if !ok {
+				continue
+			}
+			if kind == hourEntries {
+				v = 1
+			}
+			sum += v
+		}
+		return sum
+	}
+}
+
+// hoursText returns the part of the hours text h that hoursExpr reads for
+// kind: the day hours before the first "/" or "+", the night hours between
+// "/" and "+", or the overtime after "+".
+func hoursText(h string, kind hourKind) string {
+	slash, plus := strings.Index(h+"/", "/"), strings.Index(h+"+", "+")
+	switch kind {
+	case nightHours:
+		if slash >= len(h) || plus < slash {
+			return ""
+		}
+		return h[slash+1 : plus]
+	case overtimeHours:
+		if plus >= len(h) {
+			return ""
+		}
+		return h[plus+1:]
+	}
+	return h[:min(slash, plus)]
+}
+
+// entryHours returns the hours text of s if s has the shape hoursPart
+// selects for code: "CODE " followed by hours, or a leading digit for plain
+// hours, with "." as decimal separator. The code matches in any case, as
+// LEFT(…)="CODE " does in Excel.
+func entryHours(s, code string) (string, bool) {
+	if strings.Contains(s, ",") {
+		return "", false
+	}
+	if code == "" {
+		return s, s != "" && s[0] >= '0' && s[0] <= '9'
+	}
+	return cutPrefixFold(s, code+" ")
+}
+
+// cutPrefixFold is strings.CutPrefix with prefix matched case-insensitively,
+// character by character like Excel's LEFT.
+func cutPrefixFold(s, prefix string) (string, bool) {
+	n := utf8.RuneCountInString(prefix)
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	if n > 0 || !strings.EqualFold(s[:i], prefix) {
+		return s, false
+	}
+	return s[i:], true
+}
@@ -0,0 +1,301 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- Template linter ----------
+
+// LintKind classifies a template problem found by Lint.
+type LintKind string
+
+const (
+	LintUnpairedBorder  LintKind = "unpaired_border"     // &1 marker without a closing corner
+	LintInvalidMerge    LintKind = "invalid_merge"       // merge code the merge handler rejects or ignores
+	LintMergeOverlap    LintKind = "merge_overlap"       // merge range overlapping another merge
+	LintMergeBounds     LintKind = "merge_out_of_bounds" // merge range past the last row or column
+	LintOrphanFormula   LintKind = "orphan_formula"      // formula key with no employee block above it
+	LintDuplicateMarker LintKind = "duplicate_marker"    // {{start_process}} block marker repeated on a sheet
+)
+
+// Sheet limits of the xlsx format, 0-based.
+const (
+	maxRowIndex = excelize.TotalRows - 1
+	maxColIndex = excelize.MaxColumns - 1
+)
+
+// malformedMergePat matches bracketed colon codes such as [1:x] or [-1:2]
+// that look like merge codes but are not matched by mergeCodePat.
+var malformedMergePat = regexp.MustCompile(`\[[^\[\]:\s]*:[^\[\]:\s]*\]`)
+
+// LintIssue is one problem found by Lint, located by sheet and A1 cell name.
+type LintIssue struct {
+	Kind    LintKind `json:"kind"`
+	Sheet   string   `json:"sheet"`
+	Cell    string   `json:"cell"`
+	Message string   `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("sheet %q: cell %s: %s", i.Sheet, i.Cell, i.Message)
+}
+
+// LintReport lists the problems of a template, in sheet order.
+type LintReport struct {
+	Issues []LintIssue `json:"issues"`
+}
+
+// OK reports whether no problem was found.
+func (r *LintReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns nil for a clean report, or an error listing every problem.
+func (r *LintReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return fmt.Errorf("%d template problem(s):\n%s", len(r.Issues), r)
+}
+
+// String renders one problem per line.
+func (r *LintReport) String() string {
+	lines := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LintOptions configures Lint. Keys lists the formula keys of the formula
+// pass; without them only {{count …}} placeholders are checked for an
+// employee block above.
+type LintOptions struct {
+	Keys []FormulaKey
+}
+
+// Lint checks a template before it is processed:
+//
+//   - every &1 border marker has a partner on its sheet;
+//   - [r:c] and {{merge r c}} codes are valid, stay inside the sheet and do
+//     not overlap each other or the template's own merged cells;
+//   - every formula key has a {{start_process}} marker in a row above it;
+//   - no {{start_process}} block is declared twice on one sheet.
+//
+// Lint reads f only. Positions refer to the template as given, before any
+// rows are inserted.
+func Lint(f *excelize.File, opts LintOptions) (*LintReport, error) {
+	return LintContext(context.Background(), f, opts)
+}
+
+// LintContext is Lint with a context, checked between rows. A cancelled lint
+// returns an error wrapping ctx.Err().
+func LintContext(ctx context.Context, f *excelize.File, opts LintOptions) (*LintReport, error) {
+	r := &LintReport{Issues: []LintIssue{}}
+	for _, sheet := range f.GetSheetList() {
+		if err := lintSheet(ctx, f, sheet, opts, r); err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+	}
+	return r, nil
+}
+
+// mergeArea is a merge range (0-based, inclusive) and the cell declaring it.
+type mergeArea struct {
+	cell           string
+	r1, c1, r2, c2 int
+}
+
+func (a mergeArea) overlaps(b mergeArea) bool {
+	return a.r1 <= b.r2 && b.r1 <= a.r2 && a.c1 <= b.c2 && b.c1 <= a.c2
+}
+
+func lintSheet(ctx context.Context, f *excelize.File, sheet string, opts LintOptions, r *LintReport) error {
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("get rows: %w", err)
+	}
+
+	issue := func(kind LintKind, cell, format string, args ...any) {
+		r.Issues = append(r.Issues, LintIssue{Kind: kind, Sheet: sheet, Cell: cell, Message: fmt.Sprintf(format, args...)})
+	}
+
+	existing, err := templateMerges(f, sheet)
+	if err != nil {
+		return err
+	}
+
+	var (
+		border    string // cell of the pending &1 corner
+		merges    []mergeArea
+		blocks    = make(map[string]string) // block name → marker cell
+		firstMark = -1                      // row of the first {{start_process}} marker
+	)
+
+	for row, cells := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for col, value := range cells {
+			if value == "" {
+				continue
+			}
+			cell := excel.CellName(row, col)
+
+			if strings.Contains(value, "&1") {
+				if border == "" {
+					border = cell
+				} else {
+					border = ""
+				}
+			}
+
+			for _, a := range lintMerges(value, row, col, func(format string, args ...any) {
+				issue(LintInvalidMerge, cell, format, args...)
+			}) {
+				if a.r2 > maxRowIndex || a.c2 > maxColIndex {
+					issue(LintMergeBounds, cell, "merge reaches row %d, column %d; the sheet ends at row %d, column %d",
+						a.r2+1, a.c2+1, maxRowIndex+1, maxColIndex+1)
+					continue
+				}
+				for _, b := range merges {
+					if a.overlaps(b) {
+						issue(LintMergeOverlap, cell, "merge %s overlaps the merge declared at %s", mergeRef(a), b.cell)
+					}
+				}
+				for _, b := range existing {
+					if a.overlaps(b) {
+						issue(LintMergeOverlap, cell, "merge %s overlaps the merged cells %s", mergeRef(a), mergeRef(b))
+					}
+				}
+				merges = append(merges, a)
+			}
+
+			for _, p := range ParsePlaceholders(value) {
+				if p.Name == startMarker {
+					name := blockName(p.Args)
+					if first, ok := blocks[name]; ok {
+						issue(LintDuplicateMarker, cell, "block %q already declared at %s", name, first)
+					} else {
+						blocks[name] = cell
+					}
+					if firstMark < 0 {
+						firstMark = row
+					}
+				}
+			}
+
+			if key, ok := formulaKeyIn(value, opts.Keys); ok && (firstMark < 0 || firstMark >= row) {
+				issue(LintOrphanFormula, cell, "formula key %s has no {{start_process}} block above it", key)
+			}
+		}
+	}
+
+	if border != "" {
+		issue(LintUnpairedBorder, border, "&1 marker has no closing corner")
+	}
+	return nil
+}
+
+// lintMerges returns the merge ranges declared in value at (row, col). Codes
+// the merge handler would reject, or ignore although they look like merge
+// codes, are reported through invalid. Only the first code of a cell is
+// applied, so further codes are reported too.
+func lintMerges(value string, row, col int, invalid func(format string, args ...any)) []mergeArea {
+	type code struct {
+		text       string
+		rows, cols string
+	}
+
+	var codes []code
+	for _, m := range mergeCodePat.FindAllStringSubmatch(value, -1) {
+		codes = append(codes, code{m[0], m[1], m[2]})
+	}
+	for _, p := range ParsePlaceholders(value) {
+		if p.Name != "merge" {
+			continue
+		}
+		if len(p.Args) != 2 {
+			invalid("%s: want 2 arguments (rows, cols), got %d", p.Text, len(p.Args))
+			continue
+		}
+		codes = append(codes, code{p.Text, p.Args[0], p.Args[1]})
+	}
+	for _, text := range malformedMergePat.FindAllString(value, -1) {
+		if !mergeCodePat.MatchString(text) {
+			invalid("%s is not a merge code (want [rows:cols] with non-negative numbers)", text)
+		}
+	}
+
+	var areas []mergeArea
+	for i, c := range codes {
+		if i > 0 {
+			invalid("%s: only the first merge code of a cell is applied", c.text)
+			continue
+		}
+		extraRows, err := strconv.Atoi(c.rows)
+		if err != nil || extraRows < 0 {
+			invalid("%s: invalid row count %q", c.text, c.rows)
+			continue
+		}
+		extraCols, err := strconv.Atoi(c.cols)
+		if err != nil || extraCols < 0 {
+			invalid("%s: invalid col count %q", c.text, c.cols)
+			continue
+		}
+		if extraRows == 0 && extraCols == 0 {
+			continue
+		}
+		areas = append(areas, mergeArea{cell: excel.CellName(row, col), r1: row, c1: col, r2: row + extraRows, c2: col + extraCols})
+	}
+	return areas
+}
+
+// templateMerges returns the merged cells already present in the template.
+func templateMerges(f *excelize.File, sheet string) ([]mergeArea, error) {
+	mcs, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("get merges: %w", err)
+	}
+
+	var areas []mergeArea
+	for _, mc := range mcs {
+		c1, r1, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		c2, r2, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		areas = append(areas, mergeArea{cell: mc.GetStartAxis(), r1: r1 - 1, c1: c1 - 1, r2: r2 - 1, c2: c2 - 1})
+	}
+	return areas, nil
+}
+
+// mergeRef returns the A1:B2 reference of a.
+func mergeRef(a mergeArea) string {
+	return excel.CellName(a.r1, a.c1) + ":" + excel.CellName(a.r2, a.c2)
+}
+
+// formulaKeyIn returns the first formula key or {{count …}} placeholder in value.
+func formulaKeyIn(value string, keys []FormulaKey) (string, bool) {
+	for _, k := range keys {
+		if k.Key != "" && strings.Contains(value, k.Key) {
+			return k.Key, true
+		}
+	}
+	for _, p := range ParsePlaceholders(value) {
+		if p.Name == countPlaceholder {
+			return p.Text, true
+		}
+	}
+	return "", false
+}
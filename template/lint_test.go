@@ -0,0 +1,138 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLint(t *testing.T) {
+	keys := []FormulaKey{{Key: "{{w}}"}}
+
+	tests := []struct {
+		name   string
+		rows   [][]string
+		merged [][2]string // merged cells already in the template
+		want   []LintIssue // Sheet is always Sheet1
+	}{
+		{
+			name: "clean",
+			rows: [][]string{
+				{"&1 {{start_process}}", "[1:1]"},
+				{"{{w}}", "", "{{count W}}", "&1"},
+			},
+		},
+		{
+			name: "formula key above the block",
+			rows: [][]string{
+				{"{{w}}"},
+				{"{{start_process}}"},
+				{"{{w}}"},
+			},
+			want: []LintIssue{
+				{Kind: LintOrphanFormula, Cell: "A1", Message: "formula key {{w}} has no {{start_process}} block above it"},
+			},
+		},
+		{
+			name: "formula key in the marker row",
+			rows: [][]string{{"{{start_process}}", "{{count W}}"}},
+			want: []LintIssue{
+				{Kind: LintOrphanFormula, Cell: "B1", Message: "formula key {{count W}} has no {{start_process}} block above it"},
+			},
+		},
+		{
+			name: "no block at all",
+			rows: [][]string{{"{{w}}", "{{x}}"}},
+			want: []LintIssue{
+				{Kind: LintOrphanFormula, Cell: "A1", Message: "formula key {{w}} has no {{start_process}} block above it"},
+			},
+		},
+		{
+			name: "duplicate block",
+			rows: [][]string{
+				{"{{start_process sales}}"},
+				{"{{start_process it}}"},
+				{"{{start_process sales}}"},
+			},
+			want: []LintIssue{
+				{Kind: LintDuplicateMarker, Cell: "A3", Message: `block "sales" already declared at A1`},
+			},
+		},
+		{
+			name: "unpaired border",
+			rows: [][]string{{"&1", "", "&1"}, {"&1"}},
+			want: []LintIssue{
+				{Kind: LintUnpairedBorder, Cell: "A2", Message: "&1 marker has no closing corner"},
+			},
+		},
+		{
+			name: "invalid merge codes",
+			rows: [][]string{{"[1:x]", "{{merge 1}}", "[1:1] {{merge 0 2}}", "{{merge -1 2}}", "[-1:2]"}},
+			want: []LintIssue{
+				{Kind: LintInvalidMerge, Cell: "A1", Message: "[1:x] is not a merge code (want [rows:cols] with non-negative numbers)"},
+				{Kind: LintInvalidMerge, Cell: "B1", Message: "{{merge 1}}: want 2 arguments (rows, cols), got 1"},
+				{Kind: LintInvalidMerge, Cell: "C1", Message: "{{merge 0 2}}: only the first merge code of a cell is applied"},
+				{Kind: LintInvalidMerge, Cell: "D1", Message: `{{merge -1 2}}: invalid row count "-1"`},
+				{Kind: LintInvalidMerge, Cell: "E1", Message: "[-1:2] is not a merge code (want [rows:cols] with non-negative numbers)"},
+			},
+		},
+		{
+			name: "overlapping merges",
+			rows: [][]string{
+				{"[1:1]", "", "", "", "{{merge 0 1}}"},
+				{"", "[0:1]"},
+				{"[0:0]", "", "", "[2:0]"},
+			},
+			merged: [][2]string{{"D4", "F4"}},
+			want: []LintIssue{
+				{Kind: LintMergeOverlap, Cell: "B2", Message: "merge B2:C2 overlaps the merge declared at A1"},
+				{Kind: LintMergeOverlap, Cell: "D3", Message: "merge D3:D5 overlaps the merged cells D4:F4"},
+			},
+		},
+		{
+			name: "merge past the sheet",
+			rows: [][]string{{"[1048576:0]", "{{merge 0 16383}}", "{{merge 0 16381}}"}},
+			want: []LintIssue{
+				{Kind: LintMergeBounds, Cell: "A1", Message: "merge reaches row 1048577, column 1; the sheet ends at row 1048576, column 16384"},
+				{Kind: LintMergeBounds, Cell: "B1", Message: "merge reaches row 1, column 16385; the sheet ends at row 1048576, column 16384"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			for r, row := range tt.rows {
+				for c, v := range row {
+					if err := f.SetCellStr("Sheet1", excel.CellName(r, c), v); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			for _, m := range tt.merged {
+				if err := f.MergeCell("Sheet1", m[0], m[1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report, err := Lint(f, LintOptions{Keys: keys})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []LintIssue{}
+			for _, issue := range tt.want {
+				issue.Sheet = "Sheet1"
+				want = append(want, issue)
+			}
+			if !reflect.DeepEqual(report.Issues, want) {
+				t.Errorf("issues:\n%s\nwant\n%s", report, &LintReport{Issues: want})
+			}
+			if report.OK() != (len(want) == 0) {
+				t.Errorf("OK() = %v with %d issues", report.OK(), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ---------- Placeholder grammar ----------
+
+var placeholderPat = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+
+// Placeholder is one parsed {{name arg1 arg2 …}} occurrence in a cell value.
+//
+// Arguments are separated by spaces; an argument containing spaces is written
+// in double quotes ("a b", with \" for a literal quote). Text is the full
+// placeholder as it appears in the cell, including the braces.
+type Placeholder struct {
+	Text string
+	Name string
+	Args []string
+}
+
+// Arg returns the value of the key=value argument with the given key.
+func (p Placeholder) Arg(key string) (string, bool) {
+	for _, a := range p.Args {
+		if k, v, ok := strings.Cut(a, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ParsePlaceholders returns every placeholder in value, in order.
+// Placeholders with unbalanced quotes are skipped.
+func ParsePlaceholders(value string) []Placeholder {
+	var out []Placeholder
+	for _, m := range placeholderPat.FindAllStringSubmatch(value, -1) {
+		fields, err := splitArgs(m[1])
+		if err != nil {
+			continue
+		}
+
+		p := Placeholder{Text: m[0]}
+		if len(fields) > 0 {
+			p.Name, p.Args = fields[0], fields[1:]
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// splitArgs splits s on spaces, honouring double-quoted arguments.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inQuote bool
+		hasArg  bool
+	)
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inQuote && r == '\\' && i+1 < len(runes):
+			i++
+			cur.WriteRune(runes[i])
+		case r == '"':
+			inQuote = !inQuote
+			hasArg = true
+		case !inQuote && (r == ' ' || r == '\t'):
+			if hasArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasArg = true
+		}
+	}
+
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if hasArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
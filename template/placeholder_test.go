@@ -0,0 +1,68 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"t", []string{"t"}},
+		{"merge 1 2", []string{"merge", "1", "2"}},
+		{"  merge\t1   2 ", []string{"merge", "1", "2"}},
+		{`count "W"`, []string{"count", "W"}},
+		{`count "a b" 8`, []string{"count", "a b", "8"}},
+		{`x "say \"hi\""`, []string{"x", `say "hi"`}},
+		{`x "a\\b"`, []string{"x", `a\b`}},
+		{`x a\b`, []string{"x", `a\b`}},
+		{`x ""`, []string{"x", ""}},
+		{`x "" y`, []string{"x", "", "y"}},
+		{`x a"b c"d`, []string{"x", "ab cd"}},
+		{`start_process dept="Sales team"`, []string{"start_process", "dept=Sales team"}},
+		{"Ýyl ÝS", []string{"Ýyl", "ÝS"}},
+	}
+
+	for _, tt := range tests {
+		got, err := splitArgs(tt.in)
+		if err != nil {
+			t.Errorf("splitArgs(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgsUnterminatedQuote(t *testing.T) {
+	for _, in := range []string{`"`, `count "W`, `x "a\"`, `a "b" "c`} {
+		if got, err := splitArgs(in); err == nil {
+			t.Errorf("splitArgs(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestParsePlaceholders(t *testing.T) {
+	got := ParsePlaceholders(`Jemi {{total:t}} {{count "a b" 2}} {{bad "x}} {{}}`)
+	want := []Placeholder{
+		{Text: "{{total:t}}", Name: "total:t", Args: []string{}},
+		{Text: `{{count "a b" 2}}`, Name: "count", Args: []string{"a b", "2"}},
+		{Text: "{{}}"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePlaceholders = %#v, want %#v", got, want)
+	}
+
+	p := ParsePlaceholders("{{start_process dept=sales group=Department}}")[0]
+	if v, ok := p.Arg("group"); !ok || v != "Department" {
+		t.Errorf("Arg(group) = %q, %v, want Department", v, ok)
+	}
+	if _, ok := p.Arg("missing"); ok {
+		t.Error("Arg(missing) found a value")
+	}
+}
@@ -1,49 +1,274 @@
 package template
 
 import (
+	"fmt"
+	"log/slog"
+	"regexp"
 	"strings"
 
-	"github.com/xuri/excelize/v2"
+	"github.com/orayew2002/rast-excel/calendar"
+	"github.com/orayew2002/rast-excel/domain"
+	"github.com/orayew2002/rast-excel/excel"
 )
 
 // HandlerFunc processes a matched template variable in an Excel cell.
-// It receives the file, sheet name, 0-based row/col indices, and the raw cell value.
-type HandlerFunc func(f *excelize.File, sheet string, row, col int, value string) error
+// ctx describes the cell, how the pattern matched and the shared run state.
+type HandlerFunc func(ctx *Context) error
 
-// Registry holds template pattern → handler mappings.
+// Match describes how a registered pattern matched a cell value.
+// Text is the matched text; Args holds the regexp capture groups or the
+// placeholder arguments (e.g. ["W", "8"] for {{count "W" 8}}).
+type Match struct {
+	Text string
+	Args []string
+}
+
+// Registry holds template pattern → handler mappings together with the
+// dependencies handlers share: the reporting period they compute day counts
+// and ranges from, and an optional working-day calendar.
 type Registry struct {
 	handlers []entry
+	claims   []func(text string) bool
+	period   domain.Period
+	calendar *calendar.Calendar
+	days     DaysOptions
+	eval     Evaluation
+	logger   *slog.Logger
 }
 
 type entry struct {
 	pattern string
+	name    string
+	match   func(value string) (Match, bool)
 	handler HandlerFunc
 }
 
-// New creates an empty Registry.
+// add appends a handler entry. name identifies the handler in inspections
+// ("" for handlers registered through the exported methods).
+func (r *Registry) add(name, pattern string, match func(value string) (Match, bool), handler HandlerFunc) {
+	r.handlers = append(r.handlers, entry{
+		pattern: pattern,
+		name:    name,
+		match:   match,
+		handler: handler,
+	})
+}
+
+// New creates an empty Registry for the current month.
 func New() *Registry {
-	return &Registry{}
+	return &Registry{period: domain.CurrentPeriod()}
+}
+
+// SetPeriod sets the reporting period used by the registered handlers.
+// Handlers read the period when they run, so it may be set before or after
+// registration.
+func (r *Registry) SetPeriod(p domain.Period) {
+	r.period = p
+}
+
+// Period returns the reporting period of the registry.
+func (r *Registry) Period() domain.Period {
+	return r.period
+}
+
+// SetCalendar sets the working-day calendar used by the registered handlers.
+func (r *Registry) SetCalendar(c *calendar.Calendar) {
+	r.calendar = c
+}
+
+// Calendar returns the working-day calendar, or nil if none was set.
+func (r *Registry) Calendar() *calendar.Calendar {
+	return r.calendar
+}
+
+// SetLogger sets the logger handed to handlers as Context.Logger.
+// Without one, slog.Default() is used.
+func (r *Registry) SetLogger(l *slog.Logger) {
+	r.logger = l
+}
+
+// Logger returns the registry logger, or slog.Default() if none was set.
+func (r *Registry) Logger() *slog.Logger {
+	if r.logger == nil {
+		return slog.Default()
+	}
+	return r.logger
 }
 
-// Register adds a handler for the given pattern (e.g. "{{days}}").
+// SetDaysOptions configures the {{days}} header and the weekend fill of the
+// attendance cells written by the employee handler.
+func (r *Registry) SetDaysOptions(opts DaysOptions) {
+	r.days = opts
+}
+
+// SetEvaluation selects whether formula handlers also compute their results
+// in Go (see Evaluation). The default is FormulasOnly.
+func (r *Registry) SetEvaluation(e Evaluation) {
+	r.eval = e
+}
+
+// Register adds a handler for the given literal pattern (e.g. "{{days}}"),
+// matched when the cell value contains it.
 // Handlers are checked in registration order; the first match wins.
 func (r *Registry) Register(pattern string, handler HandlerFunc) {
-	r.handlers = append(r.handlers, entry{pattern: pattern, handler: handler})
+	r.register("", pattern, handler)
 }
 
-// Process checks the cell value against all registered patterns.
-// If a match is found, the corresponding handler is called.
-// Returns true if a handler was executed.
-func (r *Registry) Process(f *excelize.File, sheet string, row, col int, value string) (bool, error) {
-	for _, e := range r.handlers {
-		if strings.Contains(value, e.pattern) {
-			if err := e.handler(f, sheet, row, col, value); err != nil {
-				return false, err
+// RegisterRegexp adds a handler for cell values matching re. The handler
+// receives the first match and its capture groups in Context.Match.
+func (r *Registry) RegisterRegexp(re *regexp.Regexp, handler HandlerFunc) {
+	r.registerRegexp("", re, handler)
+}
+
+// RegisterPlaceholder adds a handler for {{name …}} placeholders (see
+// ParsePlaceholders for the grammar). Context.Match holds the first
+// placeholder with that name and its arguments, e.g. {{merge 1 2}} → ["1", "2"].
+func (r *Registry) RegisterPlaceholder(name string, handler HandlerFunc) {
+	r.registerPlaceholder("", name, handler)
+}
+
+// register, registerRegexp and registerPlaceholder are Register,
+// RegisterRegexp and RegisterPlaceholder for the handlers of this package,
+// which carry a name for inspections (e.g. "merge" or "employees").
+func (r *Registry) register(name, pattern string, handler HandlerFunc) {
+	r.add(name, pattern, func(value string) (Match, bool) {
+		return Match{Text: pattern}, strings.Contains(value, pattern)
+	}, handler)
+}
+
+func (r *Registry) registerRegexp(name string, re *regexp.Regexp, handler HandlerFunc) {
+	r.add(name, re.String(), func(value string) (Match, bool) {
+		m := re.FindStringSubmatch(value)
+		if m == nil {
+			return Match{}, false
+		}
+		return Match{Text: m[0], Args: m[1:]}, true
+	}, handler)
+}
+
+func (r *Registry) registerPlaceholder(name, placeholder string, handler HandlerFunc) {
+	r.add(name, "{{"+placeholder+" …}}", func(value string) (Match, bool) {
+		if !strings.Contains(value, "{{") {
+			return Match{}, false
+		}
+		for _, p := range ParsePlaceholders(value) {
+			if p.Name == placeholder {
+				return Match{Text: p.Text, Args: p.Args}, true
 			}
+		}
+		return Match{}, false
+	}, handler)
+}
 
-			return true, nil
+// Claim records a literal placeholder that a registered handler reads from
+// the cells around the one it matched (e.g. {{/rows}} closing a block).
+// Claimed placeholders are never dispatched; Handles reports them as handled.
+func (r *Registry) Claim(pattern string) {
+	r.claims = append(r.claims, func(text string) bool {
+		return strings.Contains(text, pattern)
+	})
+}
+
+// ClaimRegexp is Claim for placeholders matching re, such as {{.Field}}.
+func (r *Registry) ClaimRegexp(re *regexp.Regexp) {
+	r.claims = append(r.claims, re.MatchString)
+}
+
+// Binding describes the handler a cell value would be dispatched to:
+// the registered pattern, the handler's name and the match. Handler is the
+// name given at registration by this package (e.g. "merge"), "" for handlers
+// registered through Register, RegisterRegexp or RegisterPlaceholder.
+type Binding struct {
+	Pattern string
+	Handler string
+	Match   Match
+}
+
+// Lookup returns the handler Process would run for value, without running it.
+func (r *Registry) Lookup(value string) (Binding, bool) {
+	e, m, ok := r.lookup(value)
+	if !ok {
+		return Binding{}, false
+	}
+	return Binding{Pattern: e.pattern, Handler: e.name, Match: m}, true
+}
+
+// Handles reports whether text — typically one placeholder — is matched by a
+// registered pattern or claimed by a handler.
+func (r *Registry) Handles(text string) bool {
+	if _, _, ok := r.lookup(text); ok {
+		return true
+	}
+	for _, claim := range r.claims {
+		if claim(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the first entry matching value.
+func (r *Registry) lookup(value string) (entry, Match, bool) {
+	for _, e := range r.handlers {
+		if m, ok := e.match(value); ok {
+			return e, m, true
 		}
 	}
+	return entry{}, Match{}, false
+}
+
+// HandlerError is returned by Registry.Process when a handler fails.
+// Pattern is the registered pattern and Text the text it matched in the cell.
+type HandlerError struct {
+	Pattern string
+	Text    string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Placeholder(), e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// Placeholder returns the matched text, or the pattern when the match is empty.
+func (e *HandlerError) Placeholder() string {
+	if e.Text == "" {
+		return e.Pattern
+	}
+	return e.Text
+}
+
+// Process checks the cell value against all registered patterns.
+// If a match is found, the corresponding handler is called with a Context
+// bound to run. Returns true if a handler was executed; a failing handler's
+// error is returned as a *HandlerError.
+func (r *Registry) Process(run *Run, sheet string, row, col int, value string) (bool, error) {
+	e, m, ok := r.lookup(value)
+	if !ok {
+		return false, nil
+	}
+
+	ctx := &Context{
+		File:       run.File,
+		Sheet:      sheet,
+		Row:        row,
+		Col:        col,
+		Value:      value,
+		Match:      m,
+		Styles:     run.Styles,
+		Period:     r.period,
+		Calendar:   r.calendar,
+		Evaluation: r.eval,
+		Logger:     r.Logger().With("sheet", sheet, "cell", excel.CellName(row, col)),
+		Store:      run.Store,
+		run:        run,
+	}
+	if err := e.handler(ctx); err != nil {
+		return false, &HandlerError{Pattern: e.pattern, Text: m.Text, Err: err}
+	}
 
-	return false, nil
+	return true, nil
 }
@@ -0,0 +1,299 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- RowsHandler ----------
+
+var (
+	rowsStartPat = regexp.MustCompile(`\{\{#rows\s+([\w-]+)\s*\}\}`)
+	rowsFieldPat = regexp.MustCompile(`\{\{\.([\w.]+)\}\}`)
+)
+
+const rowsEnd = "{{/rows}}"
+
+// RowsHandler repeats a block of template rows once per record of a named
+// source. It is the generic counterpart of RegisterEmployeeHandler for any
+// list (departments, vehicles, shift rosters…).
+//
+// The block starts at the row containing {{#rows name}} and ends at the row
+// containing {{/rows}} (both markers may sit in the same row). Cells of the
+// block reference record fields with {{.Field}}; dotted paths such as
+// {{.Driver.Name}} walk nested maps and structs. Each copy keeps the template
+// rows' styles, heights and merges.
+//
+// Records may be maps with string keys or structs (or pointers to them).
+// A missing map key renders as an empty string; a missing struct field is an
+// error. A cell holding a single numeric placeholder is written as a number.
+//
+// Usage:
+//
+//	template.NewRowsHandler().
+//	    Source("vehicles", []map[string]any{{"Plate": "AG 1234", "Seats": 4}}).
+//	    Source("departments", departments).
+//	    Register(registry)
+type RowsHandler struct {
+	sources map[string]any
+}
+
+// NewRowsHandler creates a RowsHandler with no sources.
+func NewRowsHandler() *RowsHandler {
+	return &RowsHandler{sources: make(map[string]any)}
+}
+
+// Source binds name to records, which must be a slice or array.
+// Returns h so calls can be chained.
+func (h *RowsHandler) Source(name string, records any) *RowsHandler {
+	h.sources[name] = records
+	return h
+}
+
+// Register registers h into r for the {{#rows …}} block marker, claiming the
+// {{/rows}} end marker and the {{.Field}} placeholders of the block.
+func (h *RowsHandler) Register(r *Registry) {
+	r.Claim(rowsEnd)
+	r.ClaimRegexp(rowsFieldPat)
+	r.register("rows", "{{#rows ", h.handle)
+}
+
+// RegisterRowsHandler is a convenience wrapper for a single named source.
+func RegisterRowsHandler(r *Registry, name string, records any) {
+	NewRowsHandler().Source(name, records).Register(r)
+}
+
+func (h *RowsHandler) handle(ctx *Context) error {
+	f, sheet, row := ctx.File, ctx.Sheet, ctx.Row
+
+	m := rowsStartPat.FindStringSubmatch(ctx.Value)
+	if m == nil {
+		return fmt.Errorf("rows: malformed block marker %q", ctx.Value)
+	}
+	name := m[1]
+
+	source, ok := h.sources[name]
+	if !ok {
+		return fmt.Errorf("rows: unknown source %q", name)
+	}
+	records := reflect.ValueOf(source)
+	if records.Kind() != reflect.Slice && records.Kind() != reflect.Array {
+		return fmt.Errorf("rows: source %q is %T, want a slice", name, source)
+	}
+
+	block, err := readRowsBlock(f, sheet, row)
+	if err != nil {
+		return fmt.Errorf("rows %q: %w", name, err)
+	}
+
+	if err := block.expand(ctx, records.Len()); err != nil {
+		return fmt.Errorf("rows %q: %w", name, err)
+	}
+
+	for i := range records.Len() {
+		if err := block.fill(f, sheet, i, records.Index(i).Interface()); err != nil {
+			return fmt.Errorf("rows %q record %d: %w", name, i, err)
+		}
+	}
+
+	return nil
+}
+
+// rowsBlock is a snapshot of the template rows of one {{#rows}} block.
+type rowsBlock struct {
+	first  int        // 0-based first template row
+	cells  [][]string // template cell values, one slice per row
+	merges [][4]int   // multi-row merges inside the block: r1, c1, r2, c2 (0-based)
+}
+
+func (b *rowsBlock) height() int { return len(b.cells) }
+
+// readRowsBlock captures the block starting at first, up to the row that
+// contains {{/rows}}.
+func readRowsBlock(f *excelize.File, sheet string, first int) (*rowsBlock, error) {
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("get rows: %w", err)
+	}
+
+	b := &rowsBlock{first: first}
+	for r := first; r < len(rows); r++ {
+		b.cells = append(b.cells, append([]string(nil), rows[r]...))
+		if strings.Contains(strings.Join(rows[r], ""), rowsEnd) {
+			break
+		}
+		if r == len(rows)-1 {
+			return nil, fmt.Errorf("missing %s", rowsEnd)
+		}
+	}
+	last := first + b.height() - 1
+
+	merges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("get merges: %w", err)
+	}
+	for _, mc := range merges {
+		c1, r1, err := excelize.CellNameToCoordinates(mc.GetStartAxis())
+		if err != nil {
+			continue
+		}
+		c2, r2, err := excelize.CellNameToCoordinates(mc.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		r1, r2, c1, c2 = r1-1, r2-1, c1-1, c2-1
+		if r1 != r2 && r1 >= first && r2 <= last {
+			b.merges = append(b.merges, [4]int{r1, c1, r2, c2})
+		}
+	}
+
+	return b, nil
+}
+
+// expand turns the template block into n consecutive copies. With n == 0 the
+// template rows are removed.
+func (b *rowsBlock) expand(ctx *Context, n int) error {
+	height := b.height()
+
+	if n == 0 {
+		if err := ctx.RemoveRows(b.first, height); err != nil {
+			return fmt.Errorf("remove template rows: %w", err)
+		}
+		return nil
+	}
+
+	for i := 1; i < n; i++ {
+		for t := range height {
+			src := b.first + t
+			dst := b.first + i*height + t
+			if err := ctx.DuplicateRowTo(src, dst); err != nil {
+				return fmt.Errorf("copy template row %d: %w", t, err)
+			}
+		}
+
+		offset := i * height
+		for _, m := range b.merges {
+			topLeft := excel.CellName(m[0]+offset, m[1])
+			bottomRight := excel.CellName(m[2]+offset, m[3])
+			if err := ctx.File.MergeCell(ctx.Sheet, topLeft, bottomRight); err != nil {
+				return fmt.Errorf("merge %s:%s: %w", topLeft, bottomRight, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fill writes record into the i-th copy of the block.
+func (b *rowsBlock) fill(f *excelize.File, sheet string, i int, record any) error {
+	for t, cells := range b.cells {
+		row := b.first + i*b.height() + t
+		for c, tmpl := range cells {
+			if !strings.Contains(tmpl, "{{") {
+				continue
+			}
+			cell := excel.CellName(row, c)
+			if err := setRecordCell(f, sheet, cell, tmpl, record); err != nil {
+				return fmt.Errorf("cell %s: %w", cell, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setRecordCell renders tmpl for record into cell. A cell that is exactly one
+// placeholder of a numeric field keeps the number type.
+func setRecordCell(f *excelize.File, sheet, cell, tmpl string, record any) error {
+	tmpl = rowsStartPat.ReplaceAllString(tmpl, "")
+	tmpl = strings.ReplaceAll(tmpl, rowsEnd, "")
+
+	if m := rowsFieldPat.FindStringSubmatch(tmpl); m != nil && m[0] == tmpl {
+		v, err := recordField(record, m[1])
+		if err != nil {
+			return err
+		}
+		if isNumber(v) {
+			return f.SetCellValue(sheet, cell, v.Interface())
+		}
+		return f.SetCellStr(sheet, cell, formatField(v))
+	}
+
+	var fieldErr error
+	rendered := rowsFieldPat.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		v, err := recordField(record, rowsFieldPat.FindStringSubmatch(placeholder)[1])
+		if err != nil {
+			fieldErr = err
+			return ""
+		}
+		return formatField(v)
+	})
+	if fieldErr != nil {
+		return fieldErr
+	}
+
+	return f.SetCellStr(sheet, cell, rendered)
+}
+
+// recordField resolves a dotted field path against record.
+// It returns an invalid Value for a missing map key.
+func recordField(record any, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(record)
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, fmt.Errorf("field %q: map key is %s, want string", path, v.Type().Key())
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return reflect.Value{}, nil
+			}
+		case reflect.Struct:
+			v = v.FieldByName(name)
+			if !v.IsValid() {
+				return reflect.Value{}, fmt.Errorf("field %q: no field %s", path, name)
+			}
+		case reflect.Invalid:
+			return reflect.Value{}, nil
+		default:
+			return reflect.Value{}, fmt.Errorf("field %q: cannot select %s from %s", path, name, v.Type())
+		}
+	}
+
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+func isNumber(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func formatField(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
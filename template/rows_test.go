@@ -0,0 +1,202 @@
+package template
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+type rowsDriver struct {
+	Name string
+	Age  int
+}
+
+type rowsVehicle struct {
+	Plate  string
+	Seats  int
+	Driver *rowsDriver
+}
+
+// processRows writes rows to Sheet1 of a new workbook and runs the rows
+// handler of name on the first cell holding a {{#rows}} marker.
+func processRows(t *testing.T, rows [][]string, name string, records any) (*excelize.File, error) {
+	t.Helper()
+	f := excelize.NewFile()
+	t.Cleanup(func() { f.Close() })
+
+	marker := -1
+	for r, row := range rows {
+		for c, v := range row {
+			if err := f.SetCellStr("Sheet1", excel.CellName(r, c), v); err != nil {
+				t.Fatal(err)
+			}
+			if marker < 0 && c == 0 && strings.Contains(v, "{{#rows") {
+				marker = r
+			}
+		}
+	}
+	if marker < 0 {
+		t.Fatal("template has no {{#rows}} marker in column A")
+	}
+
+	registry := New()
+	RegisterRowsHandler(registry, name, records)
+	_, err := registry.Process(NewRun(f), "Sheet1", marker, 0, rows[marker][0])
+	return f, err
+}
+
+func TestRowsHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    [][]string
+		records any
+		want    [][]string
+	}{
+		{
+			name: "one row per record",
+			rows: [][]string{
+				{"{{#rows s}}{{.Name}}", "{{.Age}}{{/rows}}"},
+				{"Jemi"},
+			},
+			records: []map[string]any{{"Name": "Aman", "Age": 30}, {"Name": "Maral", "Age": 25}},
+			want:    [][]string{{"Aman", "30"}, {"Maral", "25"}, {"Jemi"}},
+		},
+		{
+			name: "two-row block",
+			rows: [][]string{
+				{"{{#rows s}}{{.Name}}"},
+				{"age {{.Age}}{{/rows}}"},
+				{"Jemi"},
+			},
+			records: []map[string]any{{"Name": "Aman", "Age": 30}, {"Name": "Maral", "Age": 25}},
+			want:    [][]string{{"Aman"}, {"age 30"}, {"Maral"}, {"age 25"}, {"Jemi"}},
+		},
+		{
+			name: "nested struct pointers",
+			rows: [][]string{
+				{"{{#rows s}}{{.Plate}}", "{{.Driver.Name}} ({{.Driver.Age}})", "{{.Seats}}{{/rows}}"},
+			},
+			records: []*rowsVehicle{
+				{Plate: "AG 1234", Seats: 4, Driver: &rowsDriver{Name: "Aman", Age: 30}},
+				{Plate: "AG 5678", Seats: 2},
+			},
+			want: [][]string{{"AG 1234", "Aman (30)", "4"}, {"AG 5678", " ()", "2"}},
+		},
+		{
+			name: "nested maps and missing keys",
+			rows: [][]string{
+				{"{{#rows s}}{{.Dept.Name}}", "{{.Missing}}", "{{.Dept.Missing.Deeper}}{{/rows}}"},
+			},
+			records: []map[string]any{{"Dept": map[string]any{"Name": "IT"}}},
+			want:    [][]string{{"IT"}},
+		},
+		{
+			name: "no records",
+			rows: [][]string{
+				{"Başy"},
+				{"{{#rows s}}{{.Name}}"},
+				{"{{.Age}}{{/rows}}"},
+				{"Jemi"},
+			},
+			records: []map[string]any{},
+			want:    [][]string{{"Başy"}, {"Jemi"}},
+		},
+		{
+			name: "nil slice",
+			rows: [][]string{
+				{"{{#rows s}}{{.Name}}{{/rows}}"},
+				{"Jemi"},
+			},
+			records: []rowsVehicle(nil),
+			want:    [][]string{{"Jemi"}},
+		},
+		{
+			name:    "array",
+			rows:    [][]string{{"{{#rows s}}{{.Name}}{{/rows}}"}},
+			records: [2]map[string]string{{"Name": "Aman"}, {"Name": "Maral"}},
+			want:    [][]string{{"Aman"}, {"Maral"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := processRows(t, tt.rows, "s", tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := f.GetRows("Sheet1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowsHandlerNumbers(t *testing.T) {
+	f, err := processRows(t, [][]string{
+		{"{{#rows s}}{{.Seats}}", "{{.Seats}} seats", "{{.Plate}}{{/rows}}"},
+	}, "s", []rowsVehicle{{Plate: "12", Seats: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cell string
+		want excelize.CellType
+	}{
+		{"A1", excelize.CellTypeUnset}, // a lone numeric field is written as a number
+		{"B1", excelize.CellTypeSharedString},
+		{"C1", excelize.CellTypeSharedString}, // numeric text stays text
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellType("Sheet1", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s type = %v, want %v", tt.cell, got, tt.want)
+		}
+	}
+}
+
+func TestRowsHandlerErrors(t *testing.T) {
+	records := []map[string]any{{"Name": "Aman"}}
+
+	tests := []struct {
+		name    string
+		rows    [][]string
+		source  string
+		records any
+		want    string
+	}{
+		{"unknown source", [][]string{{"{{#rows other}}{{.Name}}{{/rows}}"}}, "s", records, `rows: unknown source "other"`},
+		{"not a slice", [][]string{{"{{#rows s}}{{.Name}}{{/rows}}"}}, "s", map[string]any{"Name": "Aman"}, `rows: source "s" is map[string]interface {}, want a slice`},
+		{"missing end", [][]string{{"{{#rows s}}{{.Name}}"}, {"Jemi"}}, "s", records, `rows "s": missing {{/rows}}`},
+		{"missing struct field", [][]string{{"{{#rows s}}{{.Name}}{{/rows}}"}}, "s", []rowsVehicle{{}, {}}, `rows "s" record 0: cell A1: field "Name": no field Name`},
+		{"non-string map key", [][]string{{"{{#rows s}}{{.Name}}{{/rows}}"}}, "s", []map[int]string{{1: "x"}}, `rows "s" record 0: cell A1: field "Name": map key is int, want string`},
+		{"field of a scalar", [][]string{{"{{#rows s}}{{.Name.First}}{{/rows}}"}}, "s", records, `rows "s" record 0: cell A1: field "Name.First": cannot select First from string`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processRows(t, tt.rows, tt.source, tt.records)
+			if err == nil {
+				t.Fatalf("want an error %q", tt.want)
+			}
+			var herr *HandlerError
+			if !errors.As(err, &herr) {
+				t.Fatalf("error %T, want a *HandlerError", err)
+			}
+			if got := herr.Err.Error(); got != tt.want {
+				t.Errorf("error = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
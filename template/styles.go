@@ -1,6 +1,10 @@
 package template
 
-import "github.com/xuri/excelize/v2"
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
 
 // StyleManager caches Excel styles so each style is created only once per file.
 type StyleManager struct {
@@ -31,6 +35,27 @@ func (sm *StyleManager) Left() (int, error) {
 	})
 }
 
+// Derived returns a copy of the style baseID with mutate applied (cached per
+// baseID and key). A zero baseID derives from the default empty style.
+func (sm *StyleManager) Derived(baseID int, key string, mutate func(*excelize.Style)) (int, error) {
+	cacheKey := fmt.Sprintf("%d:%s", baseID, key)
+	if id, ok := sm.cache[cacheKey]; ok {
+		return id, nil
+	}
+
+	style := &excelize.Style{}
+	if baseID != 0 {
+		base, err := sm.file.GetStyle(baseID)
+		if err != nil {
+			return 0, err
+		}
+		style = base
+	}
+	mutate(style)
+
+	return sm.getOrCreate(cacheKey, style)
+}
+
 func (sm *StyleManager) getOrCreate(key string, style *excelize.Style) (int, error) {
 	if id, ok := sm.cache[key]; ok {
 		return id, nil
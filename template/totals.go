@@ -0,0 +1,149 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/orayew2002/rast-excel/excel"
+	"github.com/xuri/excelize/v2"
+)
+
+// ---------- {{total}} ----------
+
+// totalPat matches {{total}} and {{total:KEY}}; KEY is a formula key without
+// braces (e.g. "t" for {{t}}).
+var totalPat = regexp.MustCompile(`\{\{total(?::([^{}\s]+))?\}\}`)
+
+// formulaRecord remembers where the last formula row of a sheet wrote its
+// formulas, for the {{total}} cells below it.
+type formulaRecord struct {
+	block    string         // employee block name; "" for a fixed employee count
+	firstRow int            // 0-based, inclusive
+	lastRow  int            // 0-based, inclusive
+	cols     map[string]int // formula key → 0-based column
+
+	formulaOnly map[int]bool // columns written without computed values
+	edits       int          // structural edits of the run before the formula row
+}
+
+func formulaRecordKey(sheet string) string {
+	return "formula:last:" + sheet
+}
+
+// handleTotal writes a grand total beneath the employee rows of the nearest
+// formula row above. {{total}} totals its own column; {{total:KEY}} totals the
+// column that held {{KEY}} in the formula row.
+//
+// The total is SUBTOTAL(9, …) over the employee rows of that column, so group
+// subtotal rows inside the range are not counted twice. Like the per-employee
+// formulas, a zero total shows as an empty cell. The whole cell is replaced by
+// the formula; its style is kept.
+//
+// When the registry evaluates formulas in Go, the total is computed from the
+// values written into the employee rows.
+func handleTotal(ctx *Context) error {
+	f, sheet := ctx.File, ctx.Sheet
+
+	v, ok := ctx.Store.Get(formulaRecordKey(sheet))
+	if !ok {
+		return fmt.Errorf("total: no formula row above %s", ctx.Cell())
+	}
+	rec := v.(formulaRecord)
+
+	// The employee rows follow any rows inserted or removed since the formula
+	// row was written: block rows are re-read from the workbook, fixed rows are
+	// moved through the edits made since.
+	first, last := shiftRows(ctx.edits()[rec.edits:], sheet, rec.firstRow, rec.lastRow)
+	var groups []group
+	if rec.block != "" {
+		b, ok := findBlock(f, sheet, rec.block)
+		if !ok {
+			return fmt.Errorf("total: employee block %q is gone", rec.block)
+		}
+		first, last = b.firstRow, b.lastRow
+		groups = blockGroups(f, sheet, b)
+	}
+
+	col := ctx.Col
+	if key := ctx.Match.Args[0]; key != "" {
+		c, ok := rec.cols["{{"+key+"}}"]
+		if !ok {
+			return fmt.Errorf("total: key {{%s}} not found in the formula row", key)
+		}
+		col = c
+	}
+
+	cell := ctx.Cell()
+	if err := f.SetCellValue(sheet, cell, nil); err != nil {
+		return fmt.Errorf("total: clear %s: %w", cell, err)
+	}
+	if last < first {
+		return nil // empty block
+	}
+
+	colRange := excel.CellName(first, col) + ":" + excel.CellName(last, col)
+	formula := blankZero("SUBTOTAL(9," + colRange + ")")
+	if ctx.Evaluation == FormulasOnly || rec.formulaOnly[col] {
+		if ctx.Evaluation == ValuesOnly {
+			return fmt.Errorf("total: column of %s has no computed values", ctx.Cell())
+		}
+		if err := f.SetCellFormula(sheet, cell, formula); err != nil {
+			return fmt.Errorf("total: set formula at %s: %w", cell, err)
+		}
+		return nil
+	}
+
+	sum, err := columnSum(f, sheet, col, first, last, groups)
+	if err != nil {
+		return fmt.Errorf("total: %w", err)
+	}
+	if err := setResult(f, sheet, cell, formula, sum, true, ctx.Evaluation); err != nil {
+		return fmt.Errorf("total: set formula at %s: %w", cell, err)
+	}
+	return nil
+}
+
+// shiftRows moves the 0-based rows first to last of sheet through edits. Rows
+// inserted inside the range widen it; removed rows narrow it.
+func shiftRows(edits []Edit, sheet string, first, last int) (int, int) {
+	for _, e := range edits {
+		if e.Sheet != sheet || e.Axis != Rows {
+			continue
+		}
+		first, _ = e.Shift(first)
+		shifted, ok := e.Shift(last)
+		if !ok {
+			shifted-- // last was removed: the range ends above the removed rows
+		}
+		last = shifted
+	}
+	return first, last
+}
+
+// columnSum adds up the numeric values of col from row first to last, leaving
+// out the subtotal rows of groups as SUBTOTAL does.
+func columnSum(f *excelize.File, sheet string, col, first, last int, groups []group) (float64, error) {
+	skip := make(map[int]bool)
+	for _, g := range groups {
+		if g.subtotal {
+			skip[g.lastRow+1] = true
+		}
+	}
+
+	var sum float64
+	for row := first; row <= last; row++ {
+		if skip[row] {
+			continue
+		}
+		cell := excel.CellName(row, col)
+		v, err := f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, fmt.Errorf("read %s: %w", cell, err)
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			sum += n
+		}
+	}
+	return sum, nil
+}